@@ -0,0 +1,127 @@
+package kaeru
+
+import (
+	"reflect"
+)
+
+// hasReferences reports whether values of t can share memory with their
+// copies, which is the case when they hold a map, slice, pointer or interface
+func hasReferences(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Map, reflect.Slice, reflect.Pointer, reflect.Interface:
+		return true
+	case reflect.Array:
+		return hasReferences(t.Elem())
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			if hasReferences(t.Field(i).Type) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// copyElem deep copies a value nested in the one being copied, guarding
+// against cycles and excessive depth
+func (s *state) copyElem(inVal reflect.Value, outVal reflect.Value) error {
+	if err := s.enter(); err != nil {
+		return err
+	}
+	defer s.leave()
+
+	key, err := s.push(inVal)
+	if err != nil {
+		return err
+	}
+	defer s.pop(key)
+
+	return s.copyValue(inVal, outVal)
+}
+
+// copyValue deep copies inVal into outVal of the same type for
+// Options.DeepCopy. Nil maps, slices and pointers stay nil, unexported struct
+// fields are copied shallowly and chans and funcs are shared. The caller has
+// already entered inVal.
+func (s *state) copyValue(inVal reflect.Value, outVal reflect.Value) error {
+	if !hasReferences(inVal.Type()) {
+		outVal.Set(inVal)
+		return nil
+	}
+
+	switch inVal.Kind() {
+	case reflect.Map:
+		if inVal.IsNil() {
+			outVal.SetZero()
+			return nil
+		}
+
+		outMap := reflect.MakeMapWithSize(inVal.Type(), inVal.Len())
+		elemType := inVal.Type().Elem()
+
+		iter := inVal.MapRange()
+		for iter.Next() {
+			elem := reflect.New(elemType).Elem()
+			if err := s.copyElem(iter.Value(), elem); err != nil {
+				return err
+			}
+
+			outMap.SetMapIndex(iter.Key(), elem)
+		}
+
+		outVal.Set(outMap)
+	case reflect.Slice:
+		if inVal.IsNil() {
+			outVal.SetZero()
+			return nil
+		}
+
+		outSlice := reflect.MakeSlice(inVal.Type(), inVal.Len(), inVal.Len())
+		for i := 0; i < inVal.Len(); i++ {
+			if err := s.copyElem(inVal.Index(i), outSlice.Index(i)); err != nil {
+				return err
+			}
+		}
+
+		outVal.Set(outSlice)
+	case reflect.Array:
+		for i := 0; i < inVal.Len(); i++ {
+			if err := s.copyElem(inVal.Index(i), outVal.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Pointer, reflect.Interface:
+		if inVal.IsNil() {
+			outVal.SetZero()
+			return nil
+		}
+
+		elem := reflect.New(inVal.Elem().Type())
+		if err := s.copyElem(inVal.Elem(), elem.Elem()); err != nil {
+			return err
+		}
+
+		if inVal.Kind() == reflect.Pointer {
+			outVal.Set(elem)
+		} else {
+			outVal.Set(elem.Elem())
+		}
+	case reflect.Struct:
+		outVal.Set(inVal)
+
+		for i := 0; i < inVal.NumField(); i++ {
+			if !outVal.Field(i).CanSet() {
+				continue
+			}
+
+			if err := s.copyElem(inVal.Field(i), outVal.Field(i)); err != nil {
+				return err
+			}
+		}
+	default:
+		outVal.Set(inVal)
+	}
+
+	return nil
+}
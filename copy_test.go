@@ -0,0 +1,79 @@
+package kaeru
+
+import (
+	"reflect"
+	"testing"
+)
+
+type Snapshot struct {
+	Name   string
+	Tags   []string
+	Owner  *treeNode
+	Extra  map[string]any
+	Counts [2][]int
+}
+
+func TestParseDeepCopy(t *testing.T) {
+	p := NewParser(WithDeepCopy(true))
+
+	input := map[string]any{
+		"nested": map[string]any{"a": 1},
+		"list":   []any{map[string]any{"b": 2}},
+		"nil":    nil,
+	}
+
+	shared := map[string]any{}
+	if err := Parse(input, &shared); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	copied := map[string]any{}
+	if err := p.Parse(input, &copied); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	input["nested"].(map[string]any)["a"] = 100
+	input["list"].([]any)[0].(map[string]any)["b"] = 200
+
+	if shared["nested"].(map[string]any)["a"] != 100 {
+		t.Errorf("expected the default parse to share the input map")
+	}
+
+	expected := map[string]any{
+		"nested": map[string]any{"a": 1},
+		"list":   []any{map[string]any{"b": 2}},
+		"nil":    nil,
+	}
+
+	if !reflect.DeepEqual(copied, expected) {
+		t.Errorf("expected deep copy to be independent of the input.\nGot: %v\nWant: %v", copied, expected)
+	}
+
+	snapshot := Snapshot{
+		Name:   "a",
+		Tags:   []string{"x"},
+		Owner:  &treeNode{Value: 1},
+		Counts: [2][]int{{1}, nil},
+	}
+
+	out := new(Snapshot)
+	if err := p.Parse(snapshot, out); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(*out, snapshot) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", *out, snapshot)
+	}
+
+	snapshot.Tags[0] = "y"
+	snapshot.Owner.Value = 2
+	snapshot.Counts[0][0] = 3
+
+	if out.Tags[0] != "x" || out.Owner.Value != 1 || out.Counts[0][0] != 1 {
+		t.Errorf("expected struct copy to be independent of the input, got: %+v", *out)
+	}
+
+	if out.Extra != nil || out.Counts[1] != nil {
+		t.Errorf("expected nil references to stay nil, got: %+v", *out)
+	}
+}
@@ -0,0 +1,23 @@
+package kaeru
+
+// Decoder parses a stored input, with the shape of the decoders in
+// encoding/json and mapstructure to ease migrating from them
+type Decoder struct {
+	parser *Parser
+	input  any
+}
+
+// NewDecoder returns a Decoder for input using the default options
+func NewDecoder(input any) *Decoder {
+	return defaultParser.NewDecoder(input)
+}
+
+// NewDecoder returns a Decoder for input using the options of p
+func (p *Parser) NewDecoder(input any) *Decoder {
+	return &Decoder{parser: p, input: input}
+}
+
+// Decode parses the input into out, the same as Parse
+func (d *Decoder) Decode(out any) error {
+	return d.parser.Parse(d.input, out)
+}
@@ -0,0 +1,30 @@
+package kaeru
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestDecoder(t *testing.T) {
+	input := map[string]any{"name": "cache", "email": "ops@example.com"}
+
+	var actual Plugin
+	if err := NewDecoder(input).Decode(&actual); err != nil {
+		t.Fatalf("Decode returned an error: %v", err)
+	}
+
+	expected := Plugin{Name: "cache", Email: "ops@example.com"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Decode result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	decoder := NewParser(WithDisallowUnknownKeys(true)).NewDecoder(map[string]any{"Lat": 1.0, "Lng": 2.0, "Alt": 3.0})
+	if err := decoder.Decode(new(Coordinates)); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected the Parser options to apply, got: %v", err)
+	}
+
+	if err := NewDecoder(input).Decode(actual); !errors.Is(err, ErrOutputNotPointer) {
+		t.Errorf("expected Parse errors to be returned, got: %v", err)
+	}
+}
@@ -0,0 +1,129 @@
+package kaeru
+
+import (
+	"cmp"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// entryNames returns the key and value names of an entries tag option, which
+// default to key and value when given as a plain flag such as
+// parse:"labels,entries" rather than parse:"env,entries=name|value"
+func entryNames(opts tagOptions) (string, string, bool) {
+	if names, ok := opts.Get("entries"); ok {
+		key, value, found := strings.Cut(names, "|")
+		if found && key != "" && value != "" {
+			return key, value, true
+		}
+	}
+
+	if opts.Has("entries") {
+		return "key", "value", true
+	}
+
+	return "", "", false
+}
+
+// entriesToMap turns a list of entry objects such as
+// [{"name": "PORT", "value": "80"}] into a map keyed by the entry keys, so it
+// can be parsed into a map field. Inputs that are not lists are returned as
+// is.
+func (s *state) entriesToMap(inVal reflect.Value, keyName string, valueName string) (reflect.Value, error) {
+	for inVal.Kind() == reflect.Interface || inVal.Kind() == reflect.Pointer {
+		inVal = inVal.Elem()
+	}
+
+	if !isList(inVal.Kind()) {
+		return inVal, nil
+	}
+
+	out := make(map[any]any, inVal.Len())
+	for i := 0; i < inVal.Len(); i++ {
+		key, value, err := s.entry(inVal.Index(i), keyName, valueName)
+		if err != nil {
+			return reflect.Value{}, elementError(i, err)
+		}
+
+		if _, ok := out[key]; ok && s.opts.DisallowDuplicateKeys {
+			return reflect.Value{}, elementError(i, fmt.Errorf("%w: entry %v appears more than once", ErrDuplicateKey, key))
+		}
+
+		out[key] = value
+	}
+
+	return reflect.ValueOf(out), nil
+}
+
+// entry returns the key and value of a single entry object
+func (s *state) entry(elem reflect.Value, keyName string, valueName string) (any, any, error) {
+	for elem.Kind() == reflect.Interface || elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+
+	if elem.Kind() != reflect.Map {
+		return nil, nil, fmt.Errorf("%w: entry must be an object with %s and %s", ErrTypeMismatch, keyName, valueName)
+	}
+
+	key := s.lookupKey(elem, keyName)
+	if !key.IsValid() || key.Kind() == reflect.Interface && key.IsNil() {
+		return nil, nil, fmt.Errorf("%w: entry has no %s", ErrRequiredMissing, keyName)
+	}
+
+	// Lists and objects cannot key a map
+	if k := reflect.ValueOf(key.Interface()); !k.Comparable() {
+		return nil, nil, fmt.Errorf("%w: entry %s must be a single value, got %s", ErrTypeMismatch, keyName, k.Type())
+	}
+
+	var value any
+	if v := s.lookupKey(elem, valueName); v.IsValid() {
+		value = v.Interface()
+	}
+
+	return key.Interface(), value, nil
+}
+
+// unparseEntries is the inverse of entriesToMap, listing the entries of a map
+// sorted by key so the output is stable
+func (s *state) unparseEntries(inVal reflect.Value, keyName string, valueName string) (any, error) {
+	if inVal.IsNil() {
+		return nil, nil
+	}
+
+	keys := inVal.MapKeys()
+	slices.SortFunc(keys, compareKeys)
+
+	out := make([]any, 0, len(keys))
+
+	for _, mapKey := range keys {
+		key, err := s.unparseValue(mapKey)
+		if err != nil {
+			return nil, fmt.Errorf("error unparsing map key %v: %w", mapKey, err)
+		}
+
+		value, err := s.unparseValue(inVal.MapIndex(mapKey))
+		if err != nil {
+			return nil, fmt.Errorf("error unparsing map value %v: %w", key, err)
+		}
+
+		out = append(out, map[string]any{keyName: key, valueName: value})
+	}
+
+	return out, nil
+}
+
+// compareKeys orders map keys numerically when they are numbers, so 2 comes
+// before 10, and by their text otherwise
+func compareKeys(a, b reflect.Value) int {
+	switch a.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return cmp.Compare(a.Int(), b.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return cmp.Compare(a.Uint(), b.Uint())
+	case reflect.Float32, reflect.Float64:
+		return cmp.Compare(a.Float(), b.Float())
+	default:
+		return cmp.Compare(fmt.Sprint(a.Interface()), fmt.Sprint(b.Interface()))
+	}
+}
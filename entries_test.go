@@ -0,0 +1,104 @@
+package kaeru
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Container struct {
+	Image  string            `parse:"image"`
+	Env    map[string]string `parse:"env,entries=name|value"`
+	Labels map[string]Label  `parse:"labels,entries"`
+	Ports  map[int]string    `parse:"ports,entries=port|name"`
+}
+
+func TestParseEntries(t *testing.T) {
+	input := map[string]any{
+		"image": "kaeru:latest",
+		"env": []any{
+			map[string]any{"name": "PORT", "value": "8080"},
+			map[string]any{"name": "DEBUG", "value": "true"},
+		},
+		"labels": map[string]any{"tier": "backend"},
+		"ports": []any{
+			map[string]any{"port": 8080.0, "name": "http"},
+			map[string]any{"port": 9090.0, "name": "metrics"},
+		},
+	}
+
+	expected := &Container{
+		Image:  "kaeru:latest",
+		Env:    map[string]string{"PORT": "8080", "DEBUG": "true"},
+		Labels: map[string]Label{"tier": "backend"},
+		Ports:  map[int]string{8080: "http", 9090: "metrics"},
+	}
+
+	actual := new(Container)
+	if err := Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nExpected: %+v", actual, expected)
+	}
+
+	unparsed, err := Unparse(expected)
+	if err != nil {
+		t.Fatalf("Unparse returned an error: %v", err)
+	}
+
+	env := unparsed.(map[string]any)["env"]
+	expectedEnv := []any{
+		map[string]any{"name": "DEBUG", "value": "true"},
+		map[string]any{"name": "PORT", "value": "8080"},
+	}
+
+	if !reflect.DeepEqual(env, expectedEnv) {
+		t.Errorf("expected entries sorted by name, got: %v", env)
+	}
+
+	numbered, err := Unparse(&Container{Ports: map[int]string{10: "c", 2: "b", 1: "a"}})
+	if err != nil {
+		t.Fatalf("Unparse returned an error: %v", err)
+	}
+
+	ports := numbered.(map[string]any)["ports"]
+	expectedPorts := []any{
+		map[string]any{"port": int64(1), "name": "a"},
+		map[string]any{"port": int64(2), "name": "b"},
+		map[string]any{"port": int64(10), "name": "c"},
+	}
+
+	if !reflect.DeepEqual(ports, expectedPorts) {
+		t.Errorf("expected entries sorted by port number, got: %v", ports)
+	}
+
+	roundTrip := new(Container)
+	if err := Parse(unparsed, roundTrip); err != nil || !reflect.DeepEqual(roundTrip, expected) {
+		t.Errorf("Round trip not as expected.\nGot: %+v, %v", roundTrip, err)
+	}
+}
+
+func TestParseEntriesErrors(t *testing.T) {
+	cases := []struct {
+		env      []any
+		expected error
+		message  string
+	}{
+		{[]any{map[string]any{"value": "1"}}, ErrRequiredMissing, "entry has no name"},
+		{[]any{"PORT=1"}, ErrTypeMismatch, "index 0"},
+		{[]any{map[string]any{"name": []any{"a"}, "value": "1"}}, ErrTypeMismatch, "entry name must be a single value"},
+		{[]any{map[string]any{"name": "A"}, map[string]any{"name": map[string]any{}}}, ErrTypeMismatch, "index 1"},
+		{[]any{map[string]any{"name": "A"}, map[string]any{"name": "A"}}, ErrDuplicateKey, "index 1"},
+	}
+
+	parser := NewParser(WithDisallowDuplicateKeys(true))
+	for _, c := range cases {
+		err := parser.Parse(map[string]any{"image": "kaeru", "env": c.env, "labels": []any{}, "ports": []any{}}, new(Container))
+		if !errors.Is(err, c.expected) || !strings.Contains(err.Error(), c.message) {
+			t.Errorf("expected %v containing %q for %v, got: %v", c.expected, c.message, c.env, err)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package kaeru
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// integer is satisfied by the types that can back an integer enum
+type integer interface {
+	~int | ~int8 | ~int16 | ~int32 | ~int64 | ~uint | ~uint8 | ~uint16 | ~uint32 | ~uint64
+}
+
+// WithEnum parses string inputs into the integer enum T by looking them up in
+// names, such as WithEnum(map[string]Status{"active": Active}). Numeric inputs
+// are accepted when they equal one of the values in names. Any other input is
+// rejected with ErrTypeMismatch.
+func WithEnum[T integer](names map[string]T) Option {
+	names = maps.Clone(names)
+
+	return func(o *Options) {
+		hooks := make(map[reflect.Type]hook, len(o.hooks)+1)
+		maps.Copy(hooks, o.hooks)
+
+		hooks[reflect.TypeFor[T]()] = hook{parse: parseEnum(names)}
+		o.hooks = hooks
+	}
+}
+
+// parseEnum returns a hook that parses the names of an integer enum and its
+// values
+func parseEnum[T integer](names map[string]T) func(*state, reflect.Value, reflect.Value) error {
+	return func(s *state, inVal reflect.Value, outVal reflect.Value) error {
+		if n, ok := inVal.Interface().(json.Number); ok {
+			i, err := n.Int64()
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrTypeMismatch, err)
+			}
+
+			inVal = reflect.ValueOf(i)
+		}
+
+		switch {
+		case inVal.Kind() == reflect.String:
+			v, ok := names[inVal.String()]
+			if !ok {
+				return fmt.Errorf("%w: value %q must be one of %s", ErrTypeMismatch, inVal.String(), strings.Join(slices.Sorted(maps.Keys(names)), ", "))
+			}
+
+			outVal.Set(reflect.ValueOf(v))
+			return nil
+		case isNumber(inVal.Kind()):
+			// A fraction or an out of range number would otherwise be
+			// truncated or wrapped onto a valid value, so the number is
+			// checked and converted aside before outVal is touched
+			if err := checkInteger(inVal, outVal.Type()); err != nil {
+				return err
+			}
+
+			if overflows(inVal, outVal.Type()) {
+				return fmt.Errorf("%w: value %v of type %s does not fit in %s", ErrOverflow, inVal.Interface(), inVal.Type(), outVal.Type())
+			}
+
+			value := reflect.New(outVal.Type()).Elem()
+			if err := s.convert(inVal, value); err != nil {
+				return err
+			}
+
+			if err := checkEnumValue(value, names); err != nil {
+				return err
+			}
+
+			outVal.Set(value)
+			return nil
+		default:
+			return mismatch(inVal, outVal)
+		}
+	}
+}
+
+// checkEnumValue ensures the parsed outVal is one of the values of names
+func checkEnumValue[T integer](outVal reflect.Value, names map[string]T) error {
+	v := outVal.Interface().(T)
+	for _, value := range names {
+		if value == v {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: value %v is not a known %s", ErrTypeMismatch, v, outVal.Type())
+}
@@ -0,0 +1,80 @@
+package kaeru
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type Status int
+
+const (
+	Pending Status = iota
+	Active
+	Suspended
+)
+
+type Priority uint8
+
+type Subscription struct {
+	Status   Status
+	Priority Priority
+	Previous *Status
+}
+
+func TestParseEnums(t *testing.T) {
+	p := NewParser(
+		WithEnum(map[string]Status{"pending": Pending, "active": Active, "suspended": Suspended}),
+		WithEnum(map[string]Priority{"low": 1, "high": 9}),
+	)
+
+	var actual Subscription
+	err := p.Parse(map[string]any{"Status": "active", "Priority": "high", "Previous": 2.0}, &actual)
+	if err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	suspended := Suspended
+	expected := Subscription{Status: Active, Priority: 9, Previous: &suspended}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	var status Status
+	if err := p.Parse(json.Number("1"), &status); err != nil || status != Active {
+		t.Errorf("expected json.Number to be taken as a value, got: %v, %v", status, err)
+	}
+
+	err = p.Parse("deleted", &status)
+	if !errors.Is(err, ErrTypeMismatch) || !strings.Contains(err.Error(), "active, pending, suspended") {
+		t.Errorf("expected an unknown name to list the names, got: %v", err)
+	}
+
+	if err := p.Parse(7, &status); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected an unknown value to be rejected, got: %v", err)
+	}
+
+	status = Pending
+	if err := p.Parse(1.7, &status); !errors.Is(err, ErrNarrowing) || status != Pending {
+		t.Errorf("expected a fraction to be rejected and the output left alone, got: %v, %v", status, err)
+	}
+
+	if err := p.Parse(257, &actual.Priority); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected a value out of range to be rejected, got: %v", err)
+	}
+
+	if err := p.Parse(true, &status); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected a bool to be rejected, got: %v", err)
+	}
+
+	if err := Parse("active", &status); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected names to need WithEnum, got: %v", err)
+	}
+
+	strict := NewParser(WithRequireCustomParsers(true), WithEnum(map[string]Status{"active": Active}))
+	if err := strict.Parse(1, &status); err != nil {
+		t.Errorf("expected a registered enum to count as a custom parser, got: %v", err)
+	}
+}
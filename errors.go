@@ -0,0 +1,177 @@
+package kaeru
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Errors returned by kaeru are wrapped around one of these so callers can
+// branch on the category with errors.Is
+var (
+	ErrOutputNotPointer  = errors.New("output must be a pointer")
+	ErrOutputNotSettable = errors.New("output is not settable")
+	ErrNilOutput         = errors.New("output is a nil pointer")
+	ErrRequiredMissing   = errors.New("required value is missing")
+	ErrUnsupportedKind   = errors.New("unsupported kind")
+	ErrTypeMismatch      = errors.New("type mismatch")
+	ErrOverflow          = errors.New("value overflows output")
+	ErrNarrowing         = errors.New("lossy conversion")
+	ErrMaxDepthExceeded  = errors.New("max depth exceeded")
+	ErrCycle             = errors.New("cycle detected")
+	ErrDuplicateKey      = errors.New("duplicate key")
+	ErrUnknownKey        = errors.New("unknown key")
+	ErrUnknownVariant    = errors.New("unknown variant")
+	ErrInputTooLarge     = errors.New("input too large")
+	ErrMissingParser     = errors.New("missing custom parser")
+	ErrUnknownFormat     = errors.New("unknown file format")
+)
+
+// ErrSkip can be returned from ParseAny or ParseContextual to hand the value
+// back to the default parsing, so a custom parser can handle only some inputs
+var ErrSkip = errors.New("skip custom parser")
+
+// mismatch is the error for an input that cannot be parsed into the output type
+func mismatch(inVal reflect.Value, outVal reflect.Value) error {
+	return fmt.Errorf("%w: inVal %s is not parseable to outVal %s", ErrTypeMismatch, inVal.Type(), outVal.Type())
+}
+
+// overflows reports whether the numeric inVal is out of range for the
+// integer or float kind of outType
+func overflows(inVal reflect.Value, outType reflect.Type) bool {
+	out := reflect.New(outType).Elem()
+
+	switch out.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch {
+		case inVal.CanInt():
+			return out.OverflowInt(inVal.Int())
+		case inVal.CanUint():
+			return inVal.Uint() > math.MaxInt64 || out.OverflowInt(int64(inVal.Uint()))
+		case inVal.CanFloat():
+			f := math.Trunc(inVal.Float())
+			return f < math.MinInt64 || f >= math.MaxInt64 || out.OverflowInt(int64(f))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		switch {
+		case inVal.CanInt():
+			return inVal.Int() > 0 && out.OverflowUint(uint64(inVal.Int()))
+		case inVal.CanUint():
+			return out.OverflowUint(inVal.Uint())
+		case inVal.CanFloat():
+			f := math.Trunc(inVal.Float())
+			return f >= math.MaxUint64 || (f > 0 && out.OverflowUint(uint64(f)))
+		}
+	case reflect.Float32, reflect.Float64:
+		if inVal.CanFloat() && !math.IsInf(inVal.Float(), 0) {
+			return out.OverflowFloat(inVal.Float())
+		}
+	}
+
+	return false
+}
+
+// maxValueLength is the number of characters of an input value shown in errors
+const maxValueLength = 64
+
+// fieldError wraps err with the field name and, for scalar inputs, a short
+// form of the offending value. Maps and slices are left out as the wrapped
+// error already points inside them.
+func fieldError(name string, inVal reflect.Value, err error) error {
+	if value, ok := describeValue(inVal); ok {
+		return wrapError(fmt.Sprintf("error parsing field %s: value %s", name, value), err)
+	}
+
+	return wrapError(fmt.Sprintf("error parsing field %s", name), err)
+}
+
+// errorList holds every error found under Options.CollectErrors, one per
+// line, and unwraps to each of them for errors.Is and errors.As
+type errorList []error
+
+func (l errorList) Error() string {
+	msgs := make([]string, len(l))
+	for i, err := range l {
+		msgs[i] = err.Error()
+	}
+
+	return strings.Join(msgs, "\n")
+}
+
+func (l errorList) Unwrap() []error {
+	return l
+}
+
+// joinErrors flattens errs into a single error, nil when there are none
+func joinErrors(errs []error) error {
+	var flat errorList
+	for _, err := range errs {
+		if list, ok := err.(errorList); ok {
+			flat = append(flat, list...)
+		} else if err != nil {
+			flat = append(flat, err)
+		}
+	}
+
+	switch len(flat) {
+	case 0:
+		return nil
+	case 1:
+		return flat[0]
+	default:
+		return flat
+	}
+}
+
+// wrapError prefixes err, or every error of an errorList so each one keeps
+// its full location
+func wrapError(prefix string, err error) error {
+	if list, ok := err.(errorList); ok {
+		wrapped := make(errorList, len(list))
+		for i, err := range list {
+			wrapped[i] = wrapError(prefix, err)
+		}
+
+		return wrapped
+	}
+
+	return fmt.Errorf("%s: %w", prefix, err)
+}
+
+// elementError wraps err with the index of the slice or array element
+func elementError(i int, err error) error {
+	return wrapError(fmt.Sprintf("error parsing element at index %d", i), err)
+}
+
+func describeValue(inVal reflect.Value) (string, bool) {
+	if inVal.Kind() == reflect.Interface {
+		inVal = inVal.Elem()
+	}
+
+	if !inVal.IsValid() {
+		return "", false
+	}
+
+	var value string
+	switch {
+	case inVal.Kind() == reflect.String:
+		value = truncate(inVal.String())
+		return strconv.Quote(value), true
+	case isPrimitive(inVal.Kind()) || inVal.Type() == timeType:
+		return truncate(fmt.Sprint(inVal.Interface())), true
+	default:
+		return "", false
+	}
+}
+
+func truncate(s string) string {
+	runes := []rune(s)
+	if len(runes) <= maxValueLength {
+		return s
+	}
+
+	return string(runes[:maxValueLength]) + "..."
+}
@@ -0,0 +1,118 @@
+package kaeru
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fileFormat decodes the contents of a file for ParseFile
+type fileFormat struct {
+	unmarshal func(data []byte, v any) error
+}
+
+// WithFileFormat registers a format for ParseFile and ParseFileAs under name,
+// picked for files with one of the extensions such as ".yaml". unmarshal has
+// the signature of json.Unmarshal, so the Unmarshal function of a YAML or TOML
+// package can be passed as is, such as
+// WithFileFormat("yaml", yaml.Unmarshal, ".yaml", ".yml"). JSON is built in.
+func WithFileFormat(name string, unmarshal func(data []byte, v any) error, extensions ...string) Option {
+	return func(o *Options) {
+		formats := make(map[string]fileFormat, len(o.formats)+1)
+		maps.Copy(formats, o.formats)
+		formats[name] = fileFormat{unmarshal: unmarshal}
+		o.formats = formats
+
+		exts := make(map[string]string, len(o.extensions)+len(extensions))
+		maps.Copy(exts, o.extensions)
+		for _, ext := range extensions {
+			exts[strings.ToLower(ext)] = name
+		}
+		o.extensions = exts
+	}
+}
+
+// ParseFile reads the file at path and parses it into output, decoding it
+// with the format registered for its extension. Files ending in .json are
+// decoded as with ParseJsonBytes, other formats such as YAML and TOML must be
+// registered with WithFileFormat first.
+func ParseFile(path string, output any) error {
+	return defaultParser.ParseFile(path, output)
+}
+
+func (p *Parser) ParseFile(path string, output any) error {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	format, ok := p.opts.extensions[ext]
+	if !ok && ext == ".json" {
+		format, ok = "json", true
+	}
+
+	if !ok {
+		return fmt.Errorf("%w: no format for the extension %q of %s, only JSON is built in and others must be registered with WithFileFormat", ErrUnknownFormat, ext, path)
+	}
+
+	return p.ParseFileAs(path, format, output)
+}
+
+// ParseFileAs reads the file at path and parses it into output, decoding it
+// with the named format whatever its extension
+func ParseFileAs(path string, format string, output any) error {
+	return defaultParser.ParseFileAs(path, format, output)
+}
+
+func (p *Parser) ParseFileAs(path string, format string, output any) error {
+	decoder, ok := p.opts.formats[format]
+	if !ok && format != "json" {
+		return fmt.Errorf("%w: %q for %s, only JSON is built in and others must be registered with WithFileFormat", ErrUnknownFormat, format, path)
+	}
+
+	data, err := p.readFile(path)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		if err := p.ParseJsonBytes(data, output); err != nil {
+			return fmt.Errorf("error parsing %s: %w", path, err)
+		}
+
+		return nil
+	}
+
+	var v any
+	if err := decoder.unmarshal(data, &v); err != nil {
+		return fmt.Errorf("error decoding %s as %s: %w", path, format, err)
+	}
+
+	if err := p.Parse(v, output); err != nil {
+		return fmt.Errorf("error parsing %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// readFile reads the file at path, failing with ErrInputTooLarge when it is
+// longer than Options.MaxInputSize
+func (p *Parser) readFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if p.opts.MaxInputSize > 0 {
+		r = &limitedReader{r: r, limit: p.opts.MaxInputSize}
+	}
+
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s: %w", path, err)
+	}
+
+	return data, nil
+}
@@ -0,0 +1,99 @@
+package kaeru
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type ServiceConfig struct {
+	Name string `parse:"name"`
+	Port int    `parse:"port"`
+}
+
+// unmarshalEnv decodes KEY=VALUE lines, standing in for a YAML or TOML
+// package in tests
+func unmarshalEnv(data []byte, v any) error {
+	out := map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("invalid line %q", line)
+		}
+
+		out[key] = value
+	}
+
+	*v.(*any) = out
+	return nil
+}
+
+func writeFile(t *testing.T, name string, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestParseFile(t *testing.T) {
+	jsonPath := writeFile(t, "service.JSON", `{"name": "api", "port": 8080}`)
+
+	var config ServiceConfig
+	if err := ParseFile(jsonPath, &config); err != nil {
+		t.Fatalf("ParseFile returned an error: %v", err)
+	}
+
+	if config != (ServiceConfig{"api", 8080}) {
+		t.Errorf("ParseFile result not as expected, got: %+v", config)
+	}
+
+	envPath := writeFile(t, "service.env", "name=worker\nport=9090")
+	if err := ParseFile(envPath, new(ServiceConfig)); !errors.Is(err, ErrUnknownFormat) || !strings.Contains(err.Error(), `".env"`) {
+		t.Errorf("expected an unknown extension error, got: %v", err)
+	}
+
+	yamlPath := writeFile(t, "service.yaml", "name: api")
+	if err := ParseFile(yamlPath, new(ServiceConfig)); !errors.Is(err, ErrUnknownFormat) || !strings.Contains(err.Error(), "WithFileFormat") {
+		t.Errorf("expected the error to point at WithFileFormat, got: %v", err)
+	}
+
+	p := NewParser(WithFileFormat("env", unmarshalEnv, ".env"), WithWeaklyTypedInput(true))
+	if err := p.ParseFile(envPath, &config); err != nil || config != (ServiceConfig{"worker", 9090}) {
+		t.Errorf("expected the registered format to be used, got: %+v, %v", config, err)
+	}
+
+	txtPath := writeFile(t, "service.txt", "name=batch\nport=7070")
+	if err := p.ParseFileAs(txtPath, "env", &config); err != nil || config != (ServiceConfig{"batch", 7070}) {
+		t.Errorf("expected ParseFileAs to override the extension, got: %+v, %v", config, err)
+	}
+
+	if err := p.ParseFileAs(txtPath, "toml", &config); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected an unknown format error, got: %v", err)
+	}
+
+	if err := p.ParseFileAs(txtPath, "json", &config); err == nil || !strings.Contains(err.Error(), txtPath) {
+		t.Errorf("expected a JSON error naming the file, got: %v", err)
+	}
+
+	badPath := writeFile(t, "bad.env", "name")
+	if err := p.ParseFile(badPath, &config); err == nil || !strings.Contains(err.Error(), "as env") {
+		t.Errorf("expected a decoding error naming the format, got: %v", err)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	if err := ParseFile(missing, &config); !errors.Is(err, fs.ErrNotExist) || !strings.Contains(err.Error(), missing) {
+		t.Errorf("expected a read error naming the file, got: %v", err)
+	}
+
+	if err := NewParser(WithMaxInputSize(8)).ParseFile(jsonPath, &config); !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("expected MaxInputSize to apply to files, got: %v", err)
+	}
+}
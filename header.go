@@ -0,0 +1,41 @@
+package kaeru
+
+import (
+	"strings"
+)
+
+// ParseHeader parses HTTP style headers, such as an http.Header or a
+// textproto.MIMEHeader, into output. See Parser.ParseHeader.
+func ParseHeader(header map[string][]string, output any) error {
+	return defaultParser.ParseHeader(header, output)
+}
+
+// ParseHeader parses HTTP style headers into output. Header names and field
+// names are matched ignoring case and dashes, so a ContentType field or a
+// field tagged Content-Type both match the Content-Type header. A header with
+// a single value can be parsed into a non slice field. Header values are
+// always text, so they are parsed as with Options.WeaklyTypedInput and a
+// field such as Retries int can be filled from X-Retries: 3.
+// Options.KeyTransform still applies to the header names before they are
+// matched.
+func (p *Parser) ParseHeader(header map[string][]string, output any) error {
+	hp := &Parser{opts: p.opts}
+	hp.opts.fieldKey = headerKey
+	hp.opts.unwrapSingle = true
+	hp.opts.WeaklyTypedInput = true
+
+	if transform := p.opts.KeyTransform; transform != nil {
+		hp.opts.KeyTransform = func(key string) string {
+			return headerKey(transform(key))
+		}
+	} else {
+		hp.opts.KeyTransform = headerKey
+	}
+
+	return hp.Parse(header, output)
+}
+
+// headerKey normalizes a header or field name for matching
+func headerKey(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "-", ""))
+}
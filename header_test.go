@@ -0,0 +1,52 @@
+package kaeru
+
+import (
+	"errors"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"testing"
+)
+
+type RequestHeaders struct {
+	ContentType string
+	RequestID   string   `parse:"X-Request-ID"`
+	Accept      []string `parse:"accept"`
+	Retries     int      `parse:"x-retries"`
+	Trace       *string
+}
+
+func TestParseHeader(t *testing.T) {
+	header := http.Header{}
+	header.Set("content-type", "application/json")
+	header.Set("X-Request-Id", "abc123")
+	header.Add("Accept", "text/html")
+	header.Add("Accept", "application/json")
+	header.Set("X-Retries", "3")
+
+	actual := new(RequestHeaders)
+	if err := ParseHeader(header, actual); err != nil {
+		t.Fatalf("ParseHeader returned an error: %v", err)
+	}
+
+	expected := &RequestHeaders{
+		ContentType: "application/json",
+		RequestID:   "abc123",
+		Accept:      []string{"text/html", "application/json"},
+		Retries:     3,
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("ParseHeader result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	header.Set("X-Retries", "many")
+	if err := ParseHeader(header, new(RequestHeaders)); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected a type mismatch for a non numeric X-Retries, got: %v", err)
+	}
+
+	mime := textproto.MIMEHeader{"Content-Type": {"text/plain"}, "X-Request-Id": {"a", "b"}, "Accept": {}, "X-Retries": {"1"}}
+	if err := ParseHeader(mime, new(RequestHeaders)); err == nil {
+		t.Errorf("expected error for a multi valued header into a string field")
+	}
+}
@@ -1,20 +1,44 @@
 package kaeru
 
-// TODO: custom error declarations
-// TODO: collect all errors and then return
-
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
 )
 
+// ParseReflect receives the raw input as a reflect.Value before anything
+// else, including stdlib hooks, ParseContextual and ParseAny, for types that
+// decode arbitrary shapes themselves such as a union picked by a
+// discriminator field. Pointer and interface inputs are already unwrapped
+// and nil inputs are left to the default and required handling. Returning
+// ErrSkip continues with the default parsing.
+type ParseReflect interface {
+	ParseReflect(v reflect.Value) error
+}
+
+// ParseAny receives the raw input before any other handling except
+// ParseReflect and ParseContextual. Returning ErrSkip continues with the
+// default parsing for inputs it does not handle.
 type ParseAny interface {
 	ParseAny(v any) error
 }
 
+// ParseContextual is like ParseAny but also receives the context passed to
+// ParseContext so parsers doing I/O can honor deadlines and cancellation
+type ParseContextual interface {
+	ParseContextual(ctx context.Context, v any) error
+}
+
 type ParseInt interface {
 	ParseInt(i int) error
 }
@@ -67,6 +91,25 @@ type ParseStringMap interface {
 	ParseStringMap(m map[string]string) error
 }
 
+// ParseStringSliceMap is used for multi-valued maps such as form values and
+// headers, any map with string keys and slices of strings as values matches
+type ParseStringSliceMap interface {
+	ParseStringSliceMap(m map[string][]string) error
+}
+
+// ParseMapReflect receives the raw input map as a reflect.Value when none of
+// ParseStringMap, ParseStringSliceMap or ParseMap match its shape, giving
+// full control over exotic maps such as map[int][]float64
+type ParseMapReflect interface {
+	ParseMapReflect(m reflect.Value) error
+}
+
+// ParseMapKey is used instead of the other Parse interfaces when the type is
+// the key of a map, allowing keys to be validated differently from values
+type ParseMapKey interface {
+	ParseMapKey(key string) error
+}
+
 type ParseMap interface {
 	ParseMap(m map[string]any) error
 }
@@ -79,27 +122,134 @@ type ParseStringSlice interface {
 	ParseStringSlice(s []string) error
 }
 
+type ParseIntSlice interface {
+	ParseIntSlice(s []int) error
+}
+
+type ParseInt64Slice interface {
+	ParseInt64Slice(s []int64) error
+}
+
+type ParseFloat64Slice interface {
+	ParseFloat64Slice(s []float64) error
+}
+
+type ParseBoolSlice interface {
+	ParseBoolSlice(s []bool) error
+}
+
+// ParseTime is called with the time when the input is a time.Time. When the
+// input is a string and the type does not implement ParseString the string is
+// parsed with Options.TimeLayout first.
+type ParseTime interface {
+	ParseTime(t time.Time) error
+}
+
 type SetDefault interface {
 	SetDefault()
 }
 
+// ParseOptions lets a struct type change the options used for its own
+// fields, such as rejecting unknown keys, without configuring the Parser.
+// The options apply to the fields and everything nested in them and are
+// undone once the struct is parsed, so parents and siblings are unaffected.
+type ParseOptions interface {
+	ParseOptions() []Option
+}
+
 func Parse(input any, output any) error {
+	return defaultParser.Parse(input, output)
+}
+
+// MustParse is like Parse but panics if parsing fails. Only intended for
+// tests and program initialization where invalid input is a programmer error.
+func MustParse(input any, output any) {
+	if err := Parse(input, output); err != nil {
+		panic(fmt.Errorf("kaeru: MustParse: %w", err))
+	}
+}
+
+// ParseContext is like Parse but aborts with the context's error once ctx is
+// done
+func ParseContext(ctx context.Context, input any, output any) error {
+	return defaultParser.ParseContext(ctx, input, output)
+}
+
+func ParseJson(r io.Reader, output any) error {
+	return defaultParser.ParseJson(r, output)
+}
+
+func ParseJsonBytes(data []byte, output any) error {
+	return defaultParser.ParseJsonBytes(data, output)
+}
+
+func (p *Parser) Parse(input any, output any) error {
+	return p.ParseContext(context.Background(), input, output)
+}
+
+func (p *Parser) ParseContext(ctx context.Context, input any, output any) error {
 	outVal := reflect.ValueOf(output)
 	// Check if output is a pointer and is addressable
 	// Is this correct?
 	if outVal.Kind() != reflect.Ptr {
-		return errors.New("output must be a pointer")
+		return ErrOutputNotPointer
+	}
+
+	if outVal.IsNil() {
+		return fmt.Errorf("%w: %s", ErrNilOutput, outVal.Type())
 	}
 
 	// Get the reflect Value and Type of both input and output
 	inVal := reflect.ValueOf(input)
 	outVal = outVal.Elem()
 
-	return parseValue(inVal, outVal)
+	s := p.newState()
+	defer p.release(s)
+
+	s.ctx = ctx
+	if _, err := s.push(reflect.ValueOf(output)); err != nil {
+		return err
+	}
+
+	return s.parseValue(inVal, outVal)
 }
 
-func ParseJson(r io.Reader, output any) error {
+// ParseValue parses input directly into out, which must be settable (see
+// reflect.Value.CanSet), such as an element reached through a pointer. It
+// exposes the machinery behind Parse for building mappers on top of kaeru.
+func ParseValue(input any, out reflect.Value) error {
+	return defaultParser.ParseValue(input, out)
+}
+
+func (p *Parser) ParseValue(input any, out reflect.Value) error {
+	if !out.IsValid() {
+		return fmt.Errorf("%w: output value is invalid", ErrOutputNotSettable)
+	}
+
+	if !out.CanSet() {
+		return fmt.Errorf("%w: output value of type %s is not settable", ErrOutputNotSettable, out.Type())
+	}
+
+	s := p.newState()
+	defer p.release(s)
+
+	return s.parseValue(reflect.ValueOf(input), out)
+}
+
+// ParseJson decodes the first JSON value read from r and parses it into
+// output. Numbers are decoded as json.Number with Options.UseNumber, reading
+// more than Options.MaxInputSize fails and Options.DisallowUnknownKeys does
+// what json.Decoder.DisallowUnknownFields would.
+func (p *Parser) ParseJson(r io.Reader, output any) error {
+	if p.opts.MaxInputSize > 0 {
+		r = &limitedReader{r: r, limit: p.opts.MaxInputSize}
+	}
+
 	decoder := json.NewDecoder(r)
+	if p.opts.UseNumber {
+		decoder.UseNumber()
+	}
+
 	var v any
 	err := decoder.Decode(&v)
 
@@ -107,10 +257,31 @@ func ParseJson(r io.Reader, output any) error {
 		return err
 	}
 
-	return Parse(v, output)
+	return p.Parse(v, output)
 }
 
-func ParseJsonBytes(data []byte, output any) error {
+func (p *Parser) ParseJsonBytes(data []byte, output any) error {
+	if p.opts.MaxInputSize > 0 && int64(len(data)) > p.opts.MaxInputSize {
+		return fmt.Errorf("%w: limit is %d bytes", ErrInputTooLarge, p.opts.MaxInputSize)
+	}
+
+	if p.opts.UseNumber {
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+
+		var v any
+		if err := decoder.Decode(&v); err != nil {
+			return err
+		}
+
+		// Match json.Unmarshal which rejects anything after the value
+		if _, err := decoder.Token(); err != io.EOF {
+			return errors.New("invalid data after top-level value")
+		}
+
+		return p.Parse(v, output)
+	}
+
 	var v any
 	err := json.Unmarshal(data, &v)
 
@@ -118,71 +289,405 @@ func ParseJsonBytes(data []byte, output any) error {
 		return err
 	}
 
-	return Parse(v, output)
+	return p.Parse(v, output)
 }
 
-func parseValue(inVal reflect.Value, outVal reflect.Value) error {
-	switch inVal.Kind() {
-	case
-		reflect.Array,
-		reflect.Chan,
-		reflect.Func,
-		reflect.Pointer,
-		reflect.Struct,
-		reflect.UnsafePointer:
-		panic("inVal is not a valid parseable value")
+// limitedReader fails with ErrInputTooLarge once more than limit bytes have
+// been read, unlike io.LimitReader which ends the input early
+type limitedReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	// Reading one byte past the limit tells an input of exactly limit bytes
+	// apart from a longer one
+	if remaining := l.limit + 1 - l.read; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+
+	if l.read > l.limit {
+		return 0, fmt.Errorf("%w: limit is %d bytes", ErrInputTooLarge, l.limit)
+	}
+
+	return n, err
+}
+
+// state holds the bookkeeping for a single call to Parse
+type state struct {
+	ctx      context.Context
+	opts     *Options
+	depth    int
+	visiting map[visit]struct{}
+
+	// layout overrides Options.TimeLayout for a field tagged with layout=
+	layout string
+
+	// path holds the field names, map keys and indexes leading to the value
+	// being parsed, only tracked when tracksPath reports true
+	path []string
+
+	// consumedMaps are cleared maps kept for reuse by parseMapToStruct and
+	// key is a scratch value for looking up fields in input maps
+	consumedMaps []map[string]struct{}
+	key          reflect.Value
+}
+
+// visit identifies a map, slice or pointer that is currently being parsed
+type visit struct {
+	ptr uintptr
+	typ reflect.Type
+	len int
+}
+
+// enter records one level of nesting and fails once MaxDepth is exceeded or
+// the context is done. Every successful enter must be paired with a call to
+// leave.
+func (s *state) enter() error {
+	if err := s.ctx.Err(); err != nil {
+		return err
+	}
+
+	if s.depth >= s.opts.MaxDepth {
+		return fmt.Errorf("%w: limit is %d", ErrMaxDepthExceeded, s.opts.MaxDepth)
+	}
+
+	s.depth++
+	return nil
+}
+
+func (s *state) leave() {
+	s.depth--
+}
+
+// push marks a map, slice or pointer as being parsed and fails if it is
+// already on the current path, which means the value refers back to itself.
+// The returned visit must be passed to pop once the value has been parsed.
+func (s *state) push(v reflect.Value) (visit, error) {
+	var key visit
+
+	switch v.Kind() {
+	case reflect.Map, reflect.Pointer:
+		if v.IsNil() {
+			return key, nil
+		}
+		key = visit{ptr: v.Pointer(), typ: v.Type()}
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return key, nil
+		}
+		key = visit{ptr: v.Pointer(), typ: v.Type(), len: v.Len()}
+	default:
+		return key, nil
+	}
+
+	if s.visiting == nil {
+		s.visiting = make(map[visit]struct{})
+	}
+
+	if _, ok := s.visiting[key]; ok {
+		return visit{}, fmt.Errorf("%w in %s", ErrCycle, v.Type())
+	}
+
+	s.visiting[key] = struct{}{}
+
+	return key, nil
+}
+
+// pop unmarks a value marked by push
+func (s *state) pop(key visit) {
+	if key.typ != nil {
+		delete(s.visiting, key)
+	}
+}
+
+// implementsOnPointer reports whether the pointer type ptr implements iface
+// while the type it points to does not
+func implementsOnPointer(ptr reflect.Type, iface reflect.Type) bool {
+	return ptr.Implements(iface) && !ptr.Elem().Implements(iface)
+}
+
+// setInterface stores inVal in the interface outVal, copying it first under
+// DeepCopy
+func (s *state) setInterface(inVal reflect.Value, outVal reflect.Value) error {
+	if s.opts.DeepCopy {
+		copied := reflect.New(inVal.Type()).Elem()
+		if err := s.copyValue(inVal, copied); err != nil {
+			return err
+		}
+
+		inVal = copied
 	}
 
+	outVal.Set(inVal)
+	return nil
+}
+
+func (s *state) parseValue(inVal reflect.Value, outVal reflect.Value) error {
 	if !outVal.CanSet() {
 		panic("outVal is not settable")
 	}
 
-	required := true
+	if err := s.enter(); err != nil {
+		return err
+	}
+	defer s.leave()
+
+	// Pointer inputs are parsed as the value they point to, a nil pointer
+	// the same as a nil input. A nil that is present, such as a JSON null,
+	// is told apart from an absent value.
+	present := inVal.IsValid()
 	if inVal.Kind() == reflect.Interface {
 		inVal = inVal.Elem()
 	}
 
-	if outVal.Kind() == reflect.Pointer {
+	for inVal.Kind() == reflect.Pointer {
+		// Methods with pointer receivers are lost on the value pointed to, so
+		// an interface output only they implement holds the pointer itself
+		if outVal.Kind() == reflect.Interface && !inVal.IsNil() && implementsOnPointer(inVal.Type(), outVal.Type()) {
+			s.trace("", inVal, outVal)
+			return s.setInterface(inVal, outVal)
+		}
+
+		key, err := s.push(inVal)
+		if err != nil {
+			return err
+		}
+		defer s.pop(key)
+
+		inVal = inVal.Elem()
+		if inVal.Kind() == reflect.Interface {
+			inVal = inVal.Elem()
+		}
+	}
+
+	switch inVal.Kind() {
+	case reflect.Func:
+		if !isSeq(inVal.Type()) {
+			return fmt.Errorf("%w: cannot parse %s", ErrUnsupportedKind, inVal.Type())
+		}
+	case reflect.Chan, reflect.UnsafePointer:
+		return fmt.Errorf("%w: cannot parse %s", ErrUnsupportedKind, inVal.Type())
+	}
+
+	required := true
+
+	if inVal.Kind() == reflect.String {
+		inVal = s.normalizeString(inVal)
+	}
+
+	key, err := s.push(inVal)
+	if err != nil {
+		return err
+	}
+	defer s.pop(key)
+
+	s.trace("", inVal, outVal)
+
+	// Walk through every level of indirection, allocating as we go
+	for outVal.Kind() == reflect.Pointer {
+		if err := s.enter(); err != nil {
+			return err
+		}
+		defer s.leave()
+
 		if outVal.IsNil() {
+			// Nil input leaves a nil pointer nil, unless the value it would
+			// point to has a default to apply
+			if !inVal.IsValid() && !hasDefault(outVal.Type().Elem()) {
+				return nil
+			}
+
 			outVal.Set(reflect.New(outVal.Type().Elem()))
+		} else {
+			key, err := s.push(outVal)
+			if err != nil {
+				return err
+			}
+			defer s.pop(key)
 		}
 		outVal = outVal.Elem()
 		required = false
 	}
-	
-	// Handle nil input values using default or returning error if required
+
+	if parser, ok := outVal.Addr().Interface().(ParseReflect); ok && inVal.IsValid() {
+		s.trace("ParseReflect", inVal, outVal)
+		if err := parser.ParseReflect(inVal); !errors.Is(err, ErrSkip) {
+			return err
+		}
+	}
+
+	hook, hasHook := s.opts.hooks[outVal.Type()]
+	if !hasHook && !canParseInto(outVal.Type()) {
+		return fmt.Errorf("%w: cannot parse into %s of kind %s", ErrUnsupportedKind, outVal.Type(), outVal.Kind())
+	}
+
+	if hasHook && hook.nullable {
+		s.trace("hook", inVal, outVal)
+		return hook.parse(s, inVal, outVal)
+	}
+
+	// Handle nil input values using default or returning error if required.
+	// Structs without a default of their own descend into their fields so
+	// those can apply their defaults. An explicit null leaves a slice nil,
+	// which is kept apart from an empty slice.
 	if !inVal.IsValid() {
 		if defaultable, ok := outVal.Addr().Interface().(SetDefault); ok {
+			s.trace("SetDefault", inVal, outVal)
 			defaultable.SetDefault()
+		} else if present && outVal.Kind() == reflect.Slice {
+			outVal.SetZero()
+		} else if required && hasExportedFields(outVal.Type()) {
+			if s.opts.SkipAbsentStructs {
+				return nil
+			}
+
+			return s.parseMapToStruct(reflect.ValueOf(map[string]any{}), outVal)
 		} else if required {
-			return errors.New("inVal is nil but must be set")
+			return ErrRequiredMissing
 		}
 
 		return nil
 	}
-	
-	if parser, ok := outVal.Addr().Interface().(ParseAny); ok {
-		return parser.ParseAny(inVal.Interface())
+
+	if hasHook {
+		s.trace("hook", inVal, outVal)
+		return hook.parse(s, inVal, outVal)
+	}
+
+	if parser, ok := outVal.Addr().Interface().(ParseContextual); ok {
+		s.trace("ParseContextual", inVal, outVal)
+		if err := parser.ParseContextual(s.ctx, inVal.Interface()); !errors.Is(err, ErrSkip) {
+			return err
+		}
+	} else if parser, ok := outVal.Addr().Interface().(ParseAny); ok {
+		s.trace("ParseAny", inVal, outVal)
+		if err := parser.ParseAny(inVal.Interface()); !errors.Is(err, ErrSkip) {
+			return err
+		}
+	}
+
+	// Raw JSON outputs capture the input subtree re-encoded as JSON, which
+	// keeps the values but not the formatting or key order of the source
+	if outVal.Type() == rawMessageType {
+		raw, err := json.Marshal(inVal.Interface())
+		if err != nil {
+			return err
+		}
+
+		outVal.SetBytes(raw)
+		return nil
+	}
+
+	if t, ok := inVal.Interface().(time.Time); ok && isTime(outVal) {
+		return s.parseTime(t, outVal)
+	}
+
+	if s.opts.stdlibInterfaces {
+		if ok, err := parseBinary(inVal, outVal); ok {
+			return err
+		}
 	}
 
-	// If types are the same we can just set them and call it a day
-	if inVal.Type() == outVal.Type() {
+	// If types are the same we can just set them and call it a day, unless
+	// the input has to be merged into an existing map or slice. For struct
+	// inputs this sets the whole subtree without visiting each field.
+	if inVal.Type() == outVal.Type() && !s.merges(outVal) {
+		if s.opts.DeepCopy {
+			return s.copyValue(inVal, outVal)
+		}
+
 		outVal.Set(inVal)
 		return nil
 	}
 
+	// Interface outputs hold the input as is when it implements them, or the
+	// variant registered for the discriminator of a map input
+	if outVal.Kind() == reflect.Interface {
+		if set, ok := s.opts.variants[outVal.Type()]; ok && inVal.Kind() == reflect.Map {
+			// The input is parsed again into the variant, which is not a
+			// cycle, so it is no longer marked as being parsed
+			s.pop(key)
+			return s.parseVariant(inVal, outVal, set)
+		}
+
+		if !inVal.Type().AssignableTo(outVal.Type()) {
+			return fmt.Errorf("%w: %s does not implement %s", ErrTypeMismatch, inVal.Type(), outVal.Type())
+		}
+
+		return s.setInterface(inVal, outVal)
+	}
+
 	inValKind := inVal.Kind()
 	outValKind := outVal.Kind()
 
 	if isPrimitive(inValKind) {
-		return parsePrimitive(inVal, outVal)
+		return s.parsePrimitive(inVal, outVal)
 	} else if inValKind == reflect.Map {
-		return parseMap(inVal, outVal)
-	} else if inValKind == reflect.Slice {
-		return parseSlice(inVal, outVal)
+		return s.parseMap(inVal, outVal)
+	} else if isList(inValKind) {
+		return s.parseSlice(inVal, outVal)
+	} else if inValKind == reflect.Func {
+		return s.parseSeq(inVal, outVal)
+	} else if inValKind == reflect.Struct {
+		return s.parseStruct(inVal, outVal)
 	} else {
-		return fmt.Errorf("unsupported kinds, in: %s, out: %s", inValKind, outValKind)
+		return fmt.Errorf("%w, in: %s, out: %s", ErrUnsupportedKind, inValKind, outValKind)
+	}
+}
+
+var (
+	setDefaultType      = reflect.TypeFor[SetDefault]()
+	parseAnyType        = reflect.TypeFor[ParseAny]()
+	parseContextualType = reflect.TypeFor[ParseContextual]()
+	parseReflectType    = reflect.TypeFor[ParseReflect]()
+)
+
+// canParseInto reports whether values of type t can be an output. Channels,
+// functions and unsafe pointers only can with a custom parser of their own.
+func canParseInto(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.UnsafePointer:
+		ptr := reflect.PointerTo(t)
+		return ptr.Implements(parseAnyType) || ptr.Implements(parseContextualType) || ptr.Implements(parseReflectType)
+	default:
+		return true
+	}
+}
+
+func hasDefault(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(setDefaultType)
+}
+
+func hasExportedFields(t reflect.Type) bool {
+	if t.Kind() != reflect.Struct {
+		return false
 	}
+
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.IsExported() {
+			return true
+		}
+
+		// Embedded structs of unexported types promote their exported fields
+		if isEmbeddedStruct(field) {
+			elem := field.Type
+			if elem.Kind() == reflect.Pointer {
+				elem = elem.Elem()
+			}
+
+			if name, _ := parseTag(field.Tag.Get("parse")); name == "" && hasExportedFields(elem) {
+				return true
+			}
+		}
+	}
+
+	return false
 }
 
 func isPrimitive(kind reflect.Kind) bool {
@@ -207,85 +712,422 @@ func isPrimitive(kind reflect.Kind) bool {
 	}
 }
 
-// inVal and outVal must be a valid primitive kind
-func parsePrimitive(inVal reflect.Value, outVal reflect.Value) error {
-	if !isPrimitive(inVal.Kind()) {
-		panic("inVal must be a primitive")
+var timeType = reflect.TypeOf(time.Time{})
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+var rawMessageType = reflect.TypeOf(json.RawMessage(nil))
+
+// isTime reports whether outVal can be set from a time.Time
+func isTime(outVal reflect.Value) bool {
+	if outVal.Type() == timeType {
+		return true
 	}
 
-	switch inVal.Kind() {
-	case reflect.String:
-		if parser, ok := outVal.Addr().Interface().(ParseString); ok {
-			return parser.ParseString(inVal.String())
+	_, ok := outVal.Addr().Interface().(ParseTime)
+	return ok
+}
+
+// isTimeField reports whether a field of type t holds times, directly or as
+// the elements of pointers, slices and arrays
+func isTimeField(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array {
+		t = t.Elem()
+	}
+
+	return t == timeType || reflect.PointerTo(t).Implements(reflect.TypeFor[ParseTime]())
+}
+
+// timeLayout returns the layout for string inputs into times
+func (s *state) timeLayout() string {
+	if s.layout != "" {
+		return s.layout
+	}
+
+	return s.opts.TimeLayout
+}
+
+// timeLocation returns the location of string inputs without an offset
+func (s *state) timeLocation() *time.Location {
+	if s.opts.TimeLocation != nil {
+		return s.opts.TimeLocation
+	}
+
+	return time.UTC
+}
+
+// outVal must satisfy isTime
+func (s *state) parseTime(t time.Time, outVal reflect.Value) error {
+	if parser, ok := outVal.Addr().Interface().(ParseTime); ok {
+		s.trace("ParseTime", reflect.ValueOf(t), outVal)
+		return parser.ParseTime(t)
+	}
+
+	outVal.Set(reflect.ValueOf(t))
+	return nil
+}
+
+// normalizeString applies Options.ExpandEnv, Options.TrimStrings and then
+// Options.StringTransform to a string input, keeping its type
+func (s *state) normalizeString(inVal reflect.Value) reflect.Value {
+	if !s.normalizes() {
+		return inVal
+	}
+
+	return reflect.ValueOf(s.normalizeText(inVal.String())).Convert(inVal.Type())
+}
+
+// normalizes reports whether normalizeString changes string inputs
+func (s *state) normalizes() bool {
+	return s.opts.ExpandEnv || s.opts.TrimStrings || s.opts.StringTransform != nil
+}
+
+func (s *state) normalizeText(str string) string {
+	if s.opts.ExpandEnv {
+		lookup := s.opts.LookupEnv
+		if lookup == nil {
+			lookup = os.Getenv
 		}
-	case reflect.Bool:
-		if outVal.Kind() == reflect.Bool {
-			outVal.Set(inVal.Convert(outVal.Type()))
-			return nil
+
+		str = os.Expand(str, lookup)
+	}
+
+	if s.opts.TrimStrings {
+		str = strings.TrimSpace(str)
+	}
+
+	if s.opts.StringTransform != nil {
+		str = s.opts.StringTransform(str)
+	}
+
+	return str
+}
+
+// normalizeAll returns values with normalizeString applied to every string,
+// including those nested in []any and map[string]any. The input is copied
+// rather than changed.
+func (s *state) normalizeAll(value any) any {
+	switch v := value.(type) {
+	case []any:
+		out := make([]any, len(v))
+		for i, elem := range v {
+			out[i] = s.normalizeAll(elem)
+		}
+
+		return out
+	case map[string]any:
+		out := make(map[string]any, len(v))
+		for key, elem := range v {
+			out[key] = s.normalizeAll(elem)
+		}
+
+		return out
+	}
+
+	if v := reflect.ValueOf(value); v.Kind() == reflect.String {
+		return s.normalizeString(v).Interface()
+	}
+
+	return value
+}
+
+// normalizeStrings returns a copy of values with normalizeText applied to
+// each of them
+func (s *state) normalizeStrings(values []string) []string {
+	out := make([]string, len(values))
+	for i, v := range values {
+		out[i] = s.normalizeText(v)
+	}
+
+	return out
+}
+
+// inVal and outVal must be a valid primitive kind
+func (s *state) parsePrimitive(inVal reflect.Value, outVal reflect.Value) error {
+	if !isPrimitive(inVal.Kind()) {
+		panic("inVal must be a primitive")
+	}
+
+	switch inVal.Kind() {
+	case reflect.String:
+		if parser, ok := outVal.Addr().Interface().(ParseString); ok {
+			s.trace("ParseString", inVal, outVal)
+			return parser.ParseString(inVal.String())
+		}
+
+		if n, ok := inVal.Interface().(json.Number); ok {
+			return s.parseNumber(n, outVal)
+		}
+
+		if isTime(outVal) {
+			t, err := time.ParseInLocation(s.timeLayout(), inVal.String(), s.timeLocation())
+			if err != nil {
+				return err
+			}
+
+			return s.parseTime(t, outVal)
+		}
+
+		// Strings into a time.Duration use Go duration syntax such as "1h30m"
+		if outVal.Type() == durationType {
+			d, err := time.ParseDuration(inVal.String())
+			if err != nil {
+				return fmt.Errorf("%w: %w", ErrTypeMismatch, err)
+			}
+
+			outVal.SetInt(int64(d))
+			return nil
+		}
+
+		if s.opts.WeaklyTypedInput {
+			if err := s.requireParser(inVal, outVal); err != nil {
+				return err
+			}
+
+			if ok, err := s.parseNumericString(inVal.String(), outVal); ok {
+				return err
+			}
+
+			if ok, err := parseBoolString(inVal.String(), outVal); ok {
+				return err
+			}
+		}
+	case reflect.Bool:
+		if outVal.Kind() == reflect.Bool {
+			if err := s.requireParser(inVal, outVal); err != nil {
+				return err
+			}
+
+			outVal.Set(inVal.Convert(outVal.Type()))
+			return nil
 		}
 	case reflect.Int8:
 		if parser, ok := outVal.Addr().Interface().(ParseInt8); ok {
+			s.trace("ParseInt8", inVal, outVal)
 			return parser.ParseInt8(int8(inVal.Int()))
 		}
 		fallthrough
 	case reflect.Int16:
 		if parser, ok := outVal.Addr().Interface().(ParseInt16); ok {
+			s.trace("ParseInt16", inVal, outVal)
 			return parser.ParseInt16(int16(inVal.Int()))
 		}
 		fallthrough
 	case reflect.Int32:
 		if parser, ok := outVal.Addr().Interface().(ParseInt32); ok {
+			s.trace("ParseInt32", inVal, outVal)
 			return parser.ParseInt32(int32(inVal.Int()))
 		}
 		fallthrough
 	case reflect.Int64:
 		if parser, ok := outVal.Addr().Interface().(ParseInt64); ok {
+			s.trace("ParseInt64", inVal, outVal)
 			return parser.ParseInt64(inVal.Int())
 		}
 		fallthrough
 	case reflect.Int:
 		if parser, ok := outVal.Addr().Interface().(ParseInt); ok {
+			s.trace("ParseInt", inVal, outVal)
 			return parser.ParseInt(int(inVal.Int()))
 		}
 	case reflect.Uint8:
 		if parser, ok := outVal.Addr().Interface().(ParseUint8); ok {
+			s.trace("ParseUint8", inVal, outVal)
 			return parser.ParseUint8(uint8(inVal.Uint()))
 		}
 		fallthrough
 	case reflect.Uint16:
 		if parser, ok := outVal.Addr().Interface().(ParseUint16); ok {
+			s.trace("ParseUint16", inVal, outVal)
 			return parser.ParseUint16(uint16(inVal.Uint()))
 		}
 		fallthrough
 	case reflect.Uint32:
 		if parser, ok := outVal.Addr().Interface().(ParseUint32); ok {
+			s.trace("ParseUint32", inVal, outVal)
 			return parser.ParseUint32(uint32(inVal.Uint()))
 		}
 		fallthrough
 	case reflect.Uint64:
 		if parser, ok := outVal.Addr().Interface().(ParseUint64); ok {
+			s.trace("ParseUint64", inVal, outVal)
 			return parser.ParseUint64(inVal.Uint())
 		}
 	case reflect.Float32:
 		if parser, ok := outVal.Addr().Interface().(ParseFloat32); ok {
+			s.trace("ParseFloat32", inVal, outVal)
 			return parser.ParseFloat32(float32(inVal.Float()))
 		}
 		fallthrough
 	case reflect.Float64:
 		if parser, ok := outVal.Addr().Interface().(ParseFloat64); ok {
+			s.trace("ParseFloat64", inVal, outVal)
 			return parser.ParseFloat64(inVal.Float())
 		}
 	}
 
+	if (s.opts.CoerceToString || s.opts.WeaklyTypedInput) && inVal.Kind() != reflect.String {
+		if parser, ok := outVal.Addr().Interface().(ParseString); ok {
+			s.trace("ParseString", inVal, outVal)
+			return parser.ParseString(fmt.Sprint(inVal.Interface()))
+		}
+	}
+
+	if err := s.requireParser(inVal, outVal); err != nil {
+		return err
+	}
+
+	if s.opts.WeaklyTypedInput && inVal.Kind() != reflect.String {
+		if ok, err := s.parseWeakScalar(inVal, outVal); ok {
+			return err
+		}
+	}
+
 	if inVal.CanConvert(outVal.Type()) {
-		outVal.Set(inVal.Convert(outVal.Type()))
+		return s.convert(inVal, outVal)
+	}
+
+	// A single value stands in for a list of one under weak typing
+	if s.opts.WeaklyTypedInput && isList(outVal.Kind()) {
+		return s.parseSlice(reflect.ValueOf([]any{inVal.Interface()}), outVal)
+	}
+
+	return mismatch(inVal, outVal)
+}
+
+// requireParser fails under RequireCustomParsers when a primitive of a named
+// type would be set by conversion because it has no parser for the input.
+// time.Duration is treated as builtin.
+func (s *state) requireParser(inVal reflect.Value, outVal reflect.Value) error {
+	if !s.opts.RequireCustomParsers || !isPrimitive(outVal.Kind()) {
+		return nil
+	}
+
+	if outVal.Type().PkgPath() == "" || outVal.Type() == durationType {
+		return nil
+	}
+
+	return fmt.Errorf("%w: %s has no parser for %s input", ErrMissingParser, outVal.Type(), inVal.Type())
+}
+
+// convert sets outVal to inVal converted to its type, rejecting conversions
+// that would wrap around, overflow or, with DisallowTypeNarrowing, lose
+// information. inVal must be convertible to the type of outVal.
+func (s *state) convert(inVal reflect.Value, outVal reflect.Value) error {
+	converted := inVal.Convert(outVal.Type())
+
+	// Negative numbers would wrap around to huge unsigned values
+	if isUnsigned(outVal.Kind()) && isNegative(inVal) {
+		return fmt.Errorf("%w: negative value %v cannot be parsed into %s", ErrOverflow, inVal.Interface(), outVal.Type())
+	}
+
+	// A float too large for float32 would silently become infinity, so it
+	// is rejected even when narrowing is allowed
+	if isFloat(outVal.Kind()) && overflows(inVal, outVal.Type()) {
+		return fmt.Errorf("%w: value %v of type %s does not fit in %s", ErrOverflow, inVal.Interface(), inVal.Type(), outVal.Type())
+	}
+
+	if s.opts.DisallowTypeNarrowing && overflows(inVal, outVal.Type()) {
+		return fmt.Errorf("%w: value %v of type %s does not fit in %s", ErrOverflow, inVal.Interface(), inVal.Type(), outVal.Type())
+	}
+
+	if s.opts.DisallowTypeNarrowing && narrows(inVal, converted) {
+		return fmt.Errorf("%w: value %v of type %s would be narrowed converting to %s", ErrNarrowing, inVal.Interface(), inVal.Type(), outVal.Type())
+	}
+
+	outVal.Set(converted)
+	return nil
+}
+
+// narrows reports whether converting inVal lost information, by checking if
+// converting back yields the original value. Rounding between float types is
+// allowed as only the precision changes.
+func narrows(inVal reflect.Value, converted reflect.Value) bool {
+	if isFloat(inVal.Kind()) && isFloat(converted.Kind()) {
+		return false
+	}
+
+	if !converted.CanConvert(inVal.Type()) {
+		return true
+	}
+
+	return !converted.Convert(inVal.Type()).Equal(inVal)
+}
+
+func isUnsigned(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return true
+	default:
+		return false
+	}
+}
+
+// isNegative reports whether a numeric value is below zero
+func isNegative(inVal reflect.Value) bool {
+	switch {
+	case inVal.CanInt():
+		return inVal.Int() < 0
+	case inVal.CanFloat():
+		return inVal.Float() < 0
+	default:
+		return false
+	}
+}
+
+func isFloat(kind reflect.Kind) bool {
+	return kind == reflect.Float32 || kind == reflect.Float64
+}
+
+// parseNumber parses a json.Number, keeping integers exact unless the output
+// only deals in floats
+func (s *state) parseNumber(n json.Number, outVal reflect.Value) error {
+	// String outputs keep the text, as converting the integer would give the
+	// rune it encodes
+	if outVal.Kind() == reflect.String {
+		if err := s.requireParser(reflect.ValueOf(n), outVal); err != nil {
+			return err
+		}
+
+		outVal.SetString(n.String())
 		return nil
 	}
 
-	return fmt.Errorf("inVal %s is not parseable to outVal %s", inVal.Type(), outVal.Type())
+	if prefersInteger(outVal) {
+		if i, err := n.Int64(); err == nil {
+			return s.parsePrimitive(reflect.ValueOf(i), outVal)
+		}
+
+		if u, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+			return s.parsePrimitive(reflect.ValueOf(u), outVal)
+		}
+	}
+
+	f, err := n.Float64()
+	if err != nil {
+		return err
+	}
+
+	return s.parsePrimitive(reflect.ValueOf(f), outVal)
+}
+
+func prefersInteger(outVal reflect.Value) bool {
+	switch outVal.Addr().Interface().(type) {
+	case ParseInt, ParseInt64, ParseUint64:
+		return true
+	case ParseFloat32, ParseFloat64:
+		return false
+	}
+
+	switch outVal.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return false
+	}
+
+	return true
 }
 
-func parseMapToMap(inVal reflect.Value, outVal reflect.Value) error {
+func (s *state) parseMapToMap(inVal reflect.Value, outVal reflect.Value) error {
 	if inVal.Kind() != reflect.Map {
 		panic("inVal must be a map")
 	}
@@ -299,29 +1141,92 @@ func parseMapToMap(inVal reflect.Value, outVal reflect.Value) error {
 	outMapKeyType := outMap.Type().Key()
 	outMapValueType := outMap.Type().Elem()
 
+	merge := s.merges(outVal)
+	if merge {
+		outMap = reflect.MakeMapWithSize(outVal.Type(), outVal.Len()+inVal.Len())
+
+		iter := outVal.MapRange()
+		for iter.Next() {
+			outMap.SetMapIndex(iter.Key(), iter.Value())
+		}
+	}
+
+	var errs []error
 	for i := 0; i < len(inMapKeys); i++ {
 		inKey := inMapKeys[i]
 		inValue := inVal.MapIndex(inKey)
 		outKey := reflect.New(outMapKeyType).Elem()
 		outValue := reflect.New(outMapValueType).Elem()
 
-		if err := parseValue(inKey, outKey); err != nil {
-			return fmt.Errorf("error parsing map key %s: %w", inKey, err)
+		if err := s.parseMapKey(inKey, outKey); err != nil {
+			err = wrapError(fmt.Sprintf("error parsing map key %s", inKey), err)
+			if !s.opts.CollectErrors {
+				return err
+			}
+
+			errs = append(errs, err)
+			continue
+		}
+
+		if merge {
+			if existing := outMap.MapIndex(outKey); existing.IsValid() {
+				outValue.Set(existing)
+			}
 		}
 
-		if err := parseValue(inValue, outValue); err != nil {
-			return fmt.Errorf("error parsing map value %s: %w", inValue, err)
+		if err := s.parseMapValue(inKey, inValue, outValue); err != nil {
+			err = wrapError(fmt.Sprintf("error parsing map value %s", inValue), err)
+			if !s.opts.CollectErrors {
+				return err
+			}
+
+			errs = append(errs, err)
+			continue
 		}
 
 		outMap.SetMapIndex(outKey, outValue)
 	}
 
+	if len(errs) > 0 {
+		return joinErrors(errs)
+	}
+
 	outVal.Set(outMap)
 
 	return nil
 }
 
-func parseMapToStruct(inVal reflect.Value, outVal reflect.Value) error {
+// merges reports whether outVal is an existing map or slice that the input
+// should be merged into rather than replace
+func (s *state) merges(outVal reflect.Value) bool {
+	if !s.opts.Merge {
+		return false
+	}
+
+	switch outVal.Kind() {
+	case reflect.Map, reflect.Slice:
+		return !outVal.IsNil()
+	default:
+		return false
+	}
+}
+
+func (s *state) parseMapKey(inKey reflect.Value, outKey reflect.Value) error {
+	if inKey.Kind() == reflect.Interface {
+		inKey = inKey.Elem()
+	}
+
+	if inKey.Kind() == reflect.String {
+		if parser, ok := outKey.Addr().Interface().(ParseMapKey); ok {
+			s.trace("ParseMapKey", inKey, outKey)
+			return parser.ParseMapKey(inKey.String())
+		}
+	}
+
+	return s.parseValue(inKey, outKey)
+}
+
+func (s *state) parseMapToStruct(inVal reflect.Value, outVal reflect.Value) error {
 	if inVal.Kind() != reflect.Map {
 		panic("inVal must be a map")
 	}
@@ -330,141 +1235,1175 @@ func parseMapToStruct(inVal reflect.Value, outVal reflect.Value) error {
 		panic("outVal must be a struct")
 	}
 
-	outType := outVal.Type()
-	for i := 0; i < outVal.NumField(); i++ {
-		field := outVal.Field(i)
-		fieldType := outType.Field(i)
-		fieldName := fieldType.Name
+	if scoped, ok := outVal.Addr().Interface().(ParseOptions); ok {
+		opts := applyOptions(*s.opts, scoped.ParseOptions())
 
-		tag := fieldType.Tag.Get("parse")
+		previous := s.opts
+		s.opts = &opts
+		defer func() { s.opts = previous }()
+	}
 
-		if tag != "" {
-			fieldName = tag
+	if s.opts.KeyTransform != nil && inVal.Type().Key().Kind() == reflect.String {
+		transformed, err := s.transformKeys(inVal)
+		if err != nil {
+			return err
 		}
 
-		// Check if the field is exported
-		if !field.CanSet() {
-			continue
+		inVal = transformed
+	}
+
+	consumed := s.consumedMap()
+	defer s.releaseConsumed(consumed)
+
+	// Under CollectErrors the unknown and leftover keys are still checked
+	// after a field fails, so every error is reported together
+	var errs []error
+
+	fields := structFields{consumed: consumed, hidden: shadowedFields(outVal.Type())}
+	if err := s.parseFields(inVal, outVal, &fields); err != nil {
+		if !s.opts.CollectErrors {
+			return err
 		}
 
-		// Look for the field in the input map
-		mapValue := inVal.MapIndex(reflect.ValueOf(fieldName))
+		errs = append(errs, err)
+	}
 
-		// Recur for nested structs or primitives
-		if err := parseValue(mapValue, field); err != nil {
-			return fmt.Errorf("error parsing field %s: %w", fieldName, err)
+	if !fields.remain.IsValid() && s.opts.OnUnknownKey != nil && len(consumed) < inVal.Len() {
+		iter := inVal.MapRange()
+		for iter.Next() {
+			if !isConsumed(consumed, iter.Key()) {
+				s.opts.OnUnknownKey(s.pathTo(fmt.Sprint(iter.Key().Interface())), iter.Value().Interface())
+			}
 		}
 	}
 
-	return nil
-}
+	if !fields.remain.IsValid() && s.opts.DisallowUnknownKeys && len(consumed) < inVal.Len() {
+		var unknown []string
 
-func parseMap(inVal reflect.Value, outVal reflect.Value) error {
-	if inVal.Kind() != reflect.Map {
-		panic("inVal must be a map")
-	}
+		iter := inVal.MapRange()
+		for iter.Next() {
+			if !isConsumed(consumed, iter.Key()) {
+				unknown = append(unknown, fmt.Sprint(iter.Key().Interface()))
+			}
+		}
 
-	if m, ok := inVal.Interface().(map[string]string); ok {
-		if parser, ok := outVal.Addr().Interface().(ParseStringMap); ok {
-			return parser.ParseStringMap(m)
+		slices.Sort(unknown)
+
+		err := fmt.Errorf("%w: %s", ErrUnknownKey, strings.Join(unknown, ", "))
+		if !s.opts.CollectErrors {
+			return err
 		}
+
+		errs = append(errs, err)
 	}
 
-	if m, ok := inVal.Interface().(map[string]any); ok {
-		if parser, ok := outVal.Addr().Interface().(ParseMap); ok {
-			return parser.ParseMap(m)
+	if fields.remain.IsValid() && len(consumed) < inVal.Len() {
+		leftover := reflect.MakeMap(inVal.Type())
+
+		iter := inVal.MapRange()
+		for iter.Next() {
+			if !isConsumed(consumed, iter.Key()) {
+				leftover.SetMapIndex(iter.Key(), iter.Value())
+			}
 		}
-	}
 
-	if outVal.Kind() == reflect.Struct {
-		return parseMapToStruct(inVal, outVal)
-	}
+		if err := s.parseLeftover(leftover, fields); err != nil {
+			err = fieldError(fields.remainName, leftover, err)
+			if !s.opts.CollectErrors {
+				return err
+			}
 
-	if outVal.Kind() == reflect.Map {
-		return parseMapToMap(inVal, outVal)
+			errs = append(errs, err)
+		}
 	}
 
-	return fmt.Errorf("inVal %s is not parseable to outVal %s", inVal.Type(), outVal.Type())
+	return joinErrors(errs)
 }
 
-func parseSliceToSlice(inVal reflect.Value, outVal reflect.Value) error {
-	if inVal.Kind() != reflect.Slice {
-		panic("inVal must be slice")
+// parseLeftover parses the keys no field used into the remain field, or adds
+// them to the entries already in an inline map
+func (s *state) parseLeftover(leftover reflect.Value, fields structFields) error {
+	if !fields.inline {
+		return s.parseValue(leftover, fields.remain)
 	}
 
-	if outVal.Kind() != reflect.Slice {
-		panic("outVal must be slice")
+	parsed := reflect.New(fields.remain.Type()).Elem()
+	if err := s.parseValue(leftover, parsed); err != nil {
+		return err
 	}
 
-	outSlice := reflect.MakeSlice(outVal.Type(), inVal.Len(), inVal.Cap())
-	for i := 0; i < inVal.Len(); i++ {
-		elem := outSlice.Index(i)
-		if err := parseValue(inVal.Index(i), elem); err != nil {
-			return err
-		}
+	if fields.remain.IsNil() {
+		fields.remain.Set(parsed)
+		return nil
+	}
+
+	iter := parsed.MapRange()
+	for iter.Next() {
+		fields.remain.SetMapIndex(iter.Key(), iter.Value())
 	}
 
-	outVal.Set(outSlice)
 	return nil
 }
 
-func parseSliceToArray(inVal reflect.Value, outVal reflect.Value) error {
-	if inVal.Kind() != reflect.Slice {
-		panic("inVal must be slice")
-	}
+// transformKeys returns a copy of the input map with Options.KeyTransform
+// applied to every key. When two keys transform to the same key the smallest
+// original key wins, or an error is returned under DisallowDuplicateKeys.
+func (s *state) transformKeys(inVal reflect.Value) (reflect.Value, error) {
+	keys := inVal.MapKeys()
+	slices.SortFunc(keys, func(a, b reflect.Value) int {
+		return strings.Compare(a.String(), b.String())
+	})
 
-	if outVal.Kind() != reflect.Array {
-		panic("outVal must be array")
-	}
+	keyType := inVal.Type().Key()
+	out := reflect.MakeMapWithSize(inVal.Type(), len(keys))
+	original := make(map[string]string, len(keys))
 
-	inLen := inVal.Len()
-	outLen := outVal.Len()
+	for _, key := range keys {
+		transformed := s.opts.KeyTransform(key.String())
 
-	// Check if the input slice is longer than the output array
-	if inLen > outLen {
-		return fmt.Errorf("input slice (length %d) is longer than output array (length %d)", inLen, outLen)
-	}
+		if first, ok := original[transformed]; ok {
+			if s.opts.DisallowDuplicateKeys {
+				return reflect.Value{}, fmt.Errorf("%w: keys %s and %s both transform to %s", ErrDuplicateKey, first, key, transformed)
+			}
 
-	// Copy elements from the input slice to the output array
-	for i := 0; i < outLen; i++ {
-		var inValIndexValue reflect.Value
-		if i < inLen {
-			inValIndexValue = inVal.Index(i)
-		} else {
-			inValIndexValue = reflect.ValueOf(nil)
+			continue
 		}
 
-		if err := parseValue(inValIndexValue, outVal.Index(i)); err != nil {
-			return fmt.Errorf("error parsing element at index %d: %w", i, err)
-		}
+		original[transformed] = key.String()
+		out.SetMapIndex(reflect.ValueOf(transformed).Convert(keyType), inVal.MapIndex(key))
 	}
 
-	return nil
+	return out, nil
 }
 
-// Parse slice input to slice output
-func parseSlice(inVal reflect.Value, outVal reflect.Value) error {
-	if inVal.Kind() != reflect.Slice {
-		panic("inVal must be slice")
+// consumedMap returns an empty map for the keys used by a struct, reusing
+// one released by an earlier struct when possible
+func (s *state) consumedMap() map[string]struct{} {
+	if n := len(s.consumedMaps); n > 0 {
+		m := s.consumedMaps[n-1]
+		s.consumedMaps = s.consumedMaps[:n-1]
+		return m
 	}
 
-	if s, ok := inVal.Interface().([]string); ok {
-		if parser, ok := outVal.Addr().Interface().(ParseStringSlice); ok {
-			return parser.ParseStringSlice(s)
-		}
-	}
+	return make(map[string]struct{})
+}
 
-	if parser, ok := outVal.Addr().Interface().(ParseSlice); ok {
-		return parser.ParseSlice(inVal.Interface().([]any))
+func (s *state) releaseConsumed(m map[string]struct{}) {
+	clear(m)
+	s.consumedMaps = append(s.consumedMaps, m)
+}
+
+// isConsumed reports whether an input map key was used by a field. Keys that
+// are not strings never match a field.
+func isConsumed(consumed map[string]struct{}, key reflect.Value) bool {
+	if key.Kind() == reflect.Interface {
+		key = key.Elem()
 	}
 
-	if outVal.Kind() == reflect.Slice {
-		return parseSliceToSlice(inVal, outVal)
+	if key.Kind() != reflect.String {
+		return false
 	}
 
-	if outVal.Kind() == reflect.Array {
-		return parseSliceToArray(inVal, outVal)
+	_, ok := consumed[key.String()]
+	return ok
+}
+
+// structFields tracks the input keys used by the fields of a struct,
+// including the fields promoted from embedded structs, and its remain or
+// inline field
+type structFields struct {
+	consumed   map[string]struct{}
+	remain     reflect.Value
+	remainName string
+
+	// inline merges the leftover keys into the remain field rather than
+	// replacing it
+	inline bool
+
+	// hidden are the promoted fields shadowed by another of the same name,
+	// depth the number of embedded structs the fields being parsed are in
+	hidden shadowed
+	depth  int
+}
+
+// parseFields parses the input map into the fields of outVal. Untagged
+// embedded structs have their fields promoted and parsed from the same map,
+// an embedded pointer is only allocated when one of its keys is present.
+func (s *state) parseFields(inVal reflect.Value, outVal reflect.Value, fields *structFields) error {
+	outType := outVal.Type()
+
+	var errs []error
+
+	for i := 0; i < outVal.NumField(); i++ {
+		field := outVal.Field(i)
+		fieldType := outType.Field(i)
+		fieldName := fieldType.Name
+
+		tag := fieldType.Tag.Get("parse")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := parseTag(tag)
+
+		if name != "" {
+			fieldName = name
+		}
+
+		// Embedded structs have their fields promoted like encoding/json, also
+		// for unexported struct types whose exported fields can still be set.
+		// A nil pointer to an unexported type cannot be allocated.
+		if name == "" && isEmbeddedStruct(fieldType) {
+			if field.Kind() == reflect.Struct && s.opts.SkipAbsentStructs && !s.keysPresent(inVal, field.Type(), fields.hidden, fields.depth+1) {
+				continue
+			}
+
+			if field.Kind() == reflect.Pointer {
+				if field.IsNil() {
+					if !field.CanSet() || !s.keysPresent(inVal, field.Type().Elem(), fields.hidden, fields.depth+1) {
+						continue
+					}
+
+					field.Set(reflect.New(field.Type().Elem()))
+				}
+
+				field = field.Elem()
+			}
+
+			fields.depth++
+			err := s.parseFields(inVal, field, fields)
+			fields.depth--
+
+			if err != nil {
+				if !s.opts.CollectErrors {
+					return err
+				}
+
+				errs = append(errs, err)
+			}
+
+			continue
+		}
+
+		// A promoted field shadowed by another of the same name is left as is
+		if fields.hidden.hides(fieldName, fields.depth) {
+			continue
+		}
+
+		// Unexported fields are skipped unless they can be set through a
+		// setter method, which is then called with the parsed value
+		var setter reflect.Value
+		if !field.CanSet() {
+			if setter = s.setter(outVal, fieldType, opts); !setter.IsValid() {
+				if err := s.checkUnexported(inVal, fieldName, fieldType, opts); err != nil {
+					if !s.opts.CollectErrors {
+						return err
+					}
+
+					errs = append(errs, err)
+				}
+
+				continue
+			}
+
+			field = reflect.New(setter.Type().In(0)).Elem()
+		}
+
+		// The remain or inline field is filled with the leftover keys after
+		// every other field has been parsed
+		if opts.Has("remain") || opts.Has("inline") {
+			if fields.remain.IsValid() {
+				return fmt.Errorf("%w: only one remain field is allowed, counting inline maps, found %s and %s", ErrUnsupportedKind, fields.remainName, fieldType.Name)
+			}
+
+			if opts.Has("inline") && field.Kind() != reflect.Map {
+				return fmt.Errorf("%w: inline field %s must be a map, got %s", ErrUnsupportedKind, fieldType.Name, field.Type())
+			}
+
+			fields.remain = field
+			fields.remainName = fieldType.Name
+			fields.inline = opts.Has("inline")
+			continue
+		}
+
+		// Look for the field in the input map, falling back to the aliases in
+		// the order they are listed when the canonical key is absent
+		mapKey, mapValue := s.lookupField(inVal, fieldName, opts)
+
+		if s.opts.DisallowDuplicateKeys && mapValue.IsValid() {
+			if other, ok := s.duplicateKey(inVal, mapKey, fieldName, opts); ok {
+				err := fmt.Errorf("%w: keys %v and %v both set field %s", ErrDuplicateKey, mapKey, other, fieldType.Name)
+				if !s.opts.CollectErrors {
+					return err
+				}
+
+				errs = append(errs, err)
+			}
+		}
+
+		if mapValue.IsValid() {
+			fields.consumed[mapKey] = struct{}{}
+		} else if s.opts.Merge {
+			// Absent keys leave the existing value untouched when merging
+			continue
+		}
+
+		// A default stands in for an absent or null value, which is otherwise
+		// rejected by required. An empty value such as "" is present and
+		// left to the parser of the field. Absent omitempty fields are left
+		// as is since Unparse drops them when empty.
+		defaultValue, hasDefault := opts.Get("default")
+		useDefault := hasDefault && isNull(mapValue)
+
+		switch {
+		case useDefault:
+			mapValue = reflect.ValueOf(defaultValue)
+		case opts.Has("required") && isNull(mapValue):
+			if !s.opts.CollectErrors {
+				return fieldError(fieldName, mapValue, ErrRequiredMissing)
+			}
+
+			errs = append(errs, fieldError(fieldName, mapValue, ErrRequiredMissing))
+			continue
+		case !mapValue.IsValid() && opts.Has("omitempty"):
+			continue
+		}
+
+		if allowed, ok := opts.Get("enum"); ok {
+			if err := checkEnum(mapValue, strings.Split(allowed, "|")); err != nil {
+				if !s.opts.CollectErrors {
+					return fieldError(fieldName, mapValue, err)
+				}
+
+				errs = append(errs, fieldError(fieldName, mapValue, err))
+				continue
+			}
+		}
+
+		// A list of entry objects fills a map field keyed by the entry keys
+		if keyName, valueName, ok := entryNames(opts); ok && mapValue.IsValid() {
+			entries, err := s.entriesToMap(mapValue, keyName, valueName)
+			if err != nil {
+				if !s.opts.CollectErrors {
+					return fieldError(fieldName, mapValue, err)
+				}
+
+				errs = append(errs, fieldError(fieldName, mapValue, err))
+				continue
+			}
+
+			mapValue = entries
+		}
+
+		// A layout only applies to time fields and is ignored for others
+		layout, hasLayout := opts.Get("layout")
+		hasLayout = hasLayout && isTimeField(field.Type())
+
+		previous := s.layout
+		if hasLayout {
+			s.layout = layout
+		}
+
+		// Recur for nested structs or primitives
+		var err error
+		if useDefault {
+			err = s.parseDefault(fieldName, mapValue, field)
+		} else {
+			err = s.parseField(fieldName, mapValue, field)
+		}
+		s.layout = previous
+
+		if err == nil && setter.IsValid() {
+			err = callSetter(setter, field)
+		}
+
+		if err != nil {
+			if !s.opts.CollectErrors {
+				return fieldError(fieldName, mapValue, err)
+			}
+
+			errs = append(errs, fieldError(fieldName, mapValue, err))
+		}
+	}
+
+	return joinErrors(errs)
+}
+
+// isNull reports whether v is absent or a nil pointer or interface
+func isNull(v reflect.Value) bool {
+	for v.Kind() == reflect.Interface || v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return true
+		}
+
+		v = v.Elem()
+	}
+
+	return !v.IsValid()
+}
+
+// parseDefault parses the text of a default tag into a field. The text is
+// parsed with WeaklyTypedInput so defaults such as "8080" or "true" fill
+// numeric and bool fields.
+func (s *state) parseDefault(name string, value reflect.Value, outVal reflect.Value) error {
+	if s.opts.WeaklyTypedInput {
+		return s.parseField(name, value, outVal)
+	}
+
+	opts := *s.opts
+	opts.WeaklyTypedInput = true
+
+	previous := s.opts
+	s.opts = &opts
+	defer func() { s.opts = previous }()
+
+	return s.parseField(name, value, outVal)
+}
+
+// checkUnexported fails under DisallowUnexportedKeys when the input has a
+// key for an unexported field that will not be set
+func (s *state) checkUnexported(inVal reflect.Value, name string, field reflect.StructField, opts tagOptions) error {
+	if !s.opts.DisallowUnexportedKeys || field.Anonymous {
+		return nil
+	}
+
+	if mapKey, mapValue := s.lookupField(inVal, name, opts); mapValue.IsValid() {
+		return fmt.Errorf("%w: %s matches unexported field %s", ErrUnknownKey, mapKey, field.Name)
+	}
+
+	return nil
+}
+
+var errorType = reflect.TypeFor[error]()
+
+// setter returns the Set<Field> method of the struct for an unexported field
+// under Options.UseSetters. The method must take a single argument and
+// return nothing or an error, anything else is not treated as a setter.
+func (s *state) setter(outVal reflect.Value, field reflect.StructField, opts tagOptions) reflect.Value {
+	if !s.opts.UseSetters || field.Anonymous || opts.Has("remain") || opts.Has("inline") || !outVal.CanAddr() {
+		return reflect.Value{}
+	}
+
+	name := []rune(field.Name)
+	name[0] = unicode.ToUpper(name[0])
+
+	method := outVal.Addr().MethodByName("Set" + string(name))
+	if !method.IsValid() {
+		return reflect.Value{}
+	}
+
+	t := method.Type()
+	if t.NumIn() != 1 || t.NumOut() > 1 || (t.NumOut() == 1 && t.Out(0) != errorType) {
+		return reflect.Value{}
+	}
+
+	return method
+}
+
+func callSetter(setter reflect.Value, value reflect.Value) error {
+	out := setter.Call([]reflect.Value{value})
+	if len(out) == 0 || out[0].IsNil() {
+		return nil
+	}
+
+	return out[0].Interface().(error)
+}
+
+// fieldKey returns the input key a field name or alias is matched against
+func (s *state) fieldKey(name string) string {
+	if s.opts.fieldKey == nil {
+		return name
+	}
+
+	return s.opts.fieldKey(name)
+}
+
+// lookupField returns the key and value for a field in the input map, trying
+// the field name first and then each alias in order
+func (s *state) lookupField(inVal reflect.Value, fieldName string, opts tagOptions) (string, reflect.Value) {
+	key := s.fieldKey(fieldName)
+	value := s.lookupKey(inVal, key)
+	for _, opt := range opts {
+		if value.IsValid() {
+			break
+		}
+
+		if alias, ok := strings.CutPrefix(opt, "alias="); ok {
+			key = s.fieldKey(alias)
+			value = s.lookupKey(inVal, key)
+		}
+	}
+
+	return key, value
+}
+
+// lookupKey returns the value for a string key in the input map, or an
+// invalid value when absent. The key is set on a reused scratch value so the
+// lookup does not allocate.
+func (s *state) lookupKey(inVal reflect.Value, key string) reflect.Value {
+	keyType := inVal.Type().Key()
+
+	switch keyType.Kind() {
+	case reflect.String:
+	case reflect.Interface:
+		keyType = stringType
+	default:
+		return reflect.Value{}
+	}
+
+	if !s.key.IsValid() || s.key.Type() != keyType {
+		s.key = reflect.New(keyType).Elem()
+	}
+
+	s.key.SetString(key)
+	return inVal.MapIndex(s.key)
+}
+
+var stringType = reflect.TypeFor[string]()
+
+// duplicateKey returns another key present in the input map that also
+// matches the field besides the key that was used
+func (s *state) duplicateKey(inVal reflect.Value, used string, fieldName string, opts tagOptions) (string, bool) {
+	candidates := []string{s.fieldKey(fieldName)}
+	for _, opt := range opts {
+		if alias, ok := strings.CutPrefix(opt, "alias="); ok {
+			candidates = append(candidates, s.fieldKey(alias))
+		}
+	}
+
+	for _, candidate := range candidates {
+		if candidate != used && s.lookupKey(inVal, candidate).IsValid() {
+			return candidate, true
+		}
+	}
+
+	return "", false
+}
+
+// isEmbeddedStruct reports whether the fields of an anonymous struct or
+// pointer to struct field are promoted into the parent
+func isEmbeddedStruct(field reflect.StructField) bool {
+	if !field.Anonymous {
+		return false
+	}
+
+	t := field.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	return t.Kind() == reflect.Struct && t != timeType
+}
+
+// keysPresent reports whether the input map has a key for any field of the
+// struct type t at the given depth, including the fields promoted from its
+// embedded structs but not those hidden by shadowing
+func (s *state) keysPresent(inVal reflect.Value, t reflect.Type, hidden shadowed, depth int) bool {
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+
+		tag := fieldType.Tag.Get("parse")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := parseTag(tag)
+		if opts.Has("remain") || opts.Has("inline") {
+			continue
+		}
+
+		if !fieldType.IsExported() && (name != "" || !isEmbeddedStruct(fieldType)) {
+			continue
+		}
+
+		if name == "" && isEmbeddedStruct(fieldType) {
+			elem := fieldType.Type
+			if elem.Kind() == reflect.Pointer {
+				elem = elem.Elem()
+			}
+
+			if s.keysPresent(inVal, elem, hidden, depth+1) {
+				return true
+			}
+
+			continue
+		}
+
+		if name == "" {
+			name = fieldType.Name
+		}
+
+		if hidden.hides(name, depth) {
+			continue
+		}
+
+		if _, mapValue := s.lookupField(inVal, name, opts); mapValue.IsValid() {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkEnum ensures a present input value is one of the allowed strings
+func checkEnum(inVal reflect.Value, allowed []string) error {
+	if inVal.Kind() == reflect.Interface {
+		inVal = inVal.Elem()
+	}
+
+	if !inVal.IsValid() {
+		return nil
+	}
+
+	if inVal.Kind() == reflect.String && slices.Contains(allowed, inVal.String()) {
+		return nil
+	}
+
+	return fmt.Errorf("%w: value %v must be one of %s", ErrTypeMismatch, inVal.Interface(), strings.Join(allowed, ", "))
+}
+
+func isString(kind reflect.Kind) bool {
+	return kind == reflect.String
+}
+
+// convertMap converts a map with string keys whose values are all of a kind
+// accepted by accept into a map[string]V. Named map, key and value types are
+// converted too. Reports false if the keys are not strings or any value is
+// rejected.
+func convertMap[V any](inVal reflect.Value, accept func(reflect.Kind) bool) (map[string]V, bool) {
+	if m, ok := inVal.Interface().(map[string]V); ok {
+		return m, true
+	}
+
+	if inVal.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+
+	outType := reflect.TypeFor[V]()
+	out := make(map[string]V, inVal.Len())
+
+	iter := inVal.MapRange()
+	for iter.Next() {
+		value := iter.Value()
+		if value.Kind() == reflect.Interface {
+			value = value.Elem()
+		}
+
+		if !value.IsValid() || !accept(value.Kind()) {
+			return nil, false
+		}
+
+		out[iter.Key().String()] = value.Convert(outType).Interface().(V)
+	}
+
+	return out, true
+}
+
+// convertStringSliceMap converts a map with string keys whose values are all
+// slices of strings into a map[string][]string, named types included
+func convertStringSliceMap(inVal reflect.Value) (map[string][]string, bool) {
+	if m, ok := inVal.Interface().(map[string][]string); ok {
+		return m, true
+	}
+
+	if inVal.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+
+	out := make(map[string][]string, inVal.Len())
+
+	iter := inVal.MapRange()
+	for iter.Next() {
+		value := iter.Value()
+		if value.Kind() == reflect.Interface {
+			value = value.Elem()
+		}
+
+		if !value.IsValid() || value.Kind() != reflect.Slice {
+			return nil, false
+		}
+
+		values, ok, _ := convertSlice[string](value, isString)
+		if !ok {
+			return nil, false
+		}
+
+		out[iter.Key().String()] = values
+	}
+
+	return out, true
+}
+
+// convertAnyMap converts any map with string keys, including named map and
+// key types, into a map[string]any
+func convertAnyMap(inVal reflect.Value) (map[string]any, bool) {
+	if m, ok := inVal.Interface().(map[string]any); ok {
+		return m, true
+	}
+
+	if inVal.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+
+	out := make(map[string]any, inVal.Len())
+
+	iter := inVal.MapRange()
+	for iter.Next() {
+		out[iter.Key().String()] = iter.Value().Interface()
+	}
+
+	return out, true
+}
+
+func (s *state) parseMap(inVal reflect.Value, outVal reflect.Value) error {
+	if inVal.Kind() != reflect.Map {
+		panic("inVal must be a map")
+	}
+
+	if parser, ok := outVal.Addr().Interface().(ParseStringMap); ok {
+		if m, ok := convertMap[string](inVal, isString); ok {
+			if s.normalizes() {
+				normalized := make(map[string]string, len(m))
+				for key, v := range m {
+					normalized[key] = s.normalizeText(v)
+				}
+
+				m = normalized
+			}
+
+			s.trace("ParseStringMap", inVal, outVal)
+			return parser.ParseStringMap(m)
+		}
+	}
+
+	if parser, ok := outVal.Addr().Interface().(ParseStringSliceMap); ok {
+		if m, ok := convertStringSliceMap(inVal); ok {
+			if s.normalizes() {
+				normalized := make(map[string][]string, len(m))
+				for key, v := range m {
+					normalized[key] = s.normalizeStrings(v)
+				}
+
+				m = normalized
+			}
+
+			s.trace("ParseStringSliceMap", inVal, outVal)
+			return parser.ParseStringSliceMap(m)
+		}
+	}
+
+	if parser, ok := outVal.Addr().Interface().(ParseMap); ok {
+		if m, ok := convertAnyMap(inVal); ok {
+			if s.normalizes() {
+				m = s.normalizeAll(m).(map[string]any)
+			}
+
+			s.trace("ParseMap", inVal, outVal)
+			return parser.ParseMap(m)
+		}
+	}
+
+	if parser, ok := outVal.Addr().Interface().(ParseMapReflect); ok {
+		s.trace("ParseMapReflect", inVal, outVal)
+		return parser.ParseMapReflect(inVal)
+	}
+
+	if outVal.Kind() == reflect.Struct {
+		return s.parseMapToStruct(inVal, outVal)
+	}
+
+	if outVal.Kind() == reflect.Map {
+		return s.parseMapToMap(inVal, outVal)
+	}
+
+	return mismatch(inVal, outVal)
+}
+
+// parseNumbers is the fast path for a []any of numbers into a slice of a
+// builtin numeric type such as []float64. Those elements can only be
+// converted, so they skip parseValue and are converted directly with the
+// same checks. Reports false when the fast path does not apply.
+func (s *state) parseNumbers(inVal reflect.Value, outVal reflect.Value) (bool, error) {
+	elemType := outVal.Type().Elem()
+	if !isNumber(elemType.Kind()) || elemType.PkgPath() != "" || s.merges(outVal) || s.opts.Trace != nil {
+		return false, nil
+	}
+
+	if _, ok := s.opts.hooks[elemType]; ok {
+		return false, nil
+	}
+
+	in, ok := inVal.Interface().([]any)
+	if !ok {
+		return false, nil
+	}
+
+	for _, v := range in {
+		switch v.(type) {
+		case float64, float32, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		default:
+			return false, nil
+		}
+	}
+
+	if err := s.enter(); err != nil {
+		return true, err
+	}
+	defer s.leave()
+
+	outSlice := reflect.MakeSlice(outVal.Type(), len(in), len(in))
+	isFloat64 := elemType.Kind() == reflect.Float64
+
+	var errs []error
+	for i, v := range in {
+		elem := outSlice.Index(i)
+
+		// JSON numbers are float64, set them without a conversion
+		if f, ok := v.(float64); ok && isFloat64 {
+			elem.SetFloat(f)
+			continue
+		}
+
+		if err := s.convert(reflect.ValueOf(v), elem); err != nil {
+			if !s.opts.CollectErrors {
+				return true, elementError(i, err)
+			}
+
+			errs = append(errs, elementError(i, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return true, joinErrors(errs)
+	}
+
+	outVal.Set(outSlice)
+	return true, nil
+}
+
+func (s *state) parseSliceToSlice(inVal reflect.Value, outVal reflect.Value) error {
+	if !isList(inVal.Kind()) {
+		panic("inVal must be slice or array")
+	}
+
+	if outVal.Kind() != reflect.Slice {
+		panic("outVal must be slice")
+	}
+
+	if s.merges(outVal) {
+		return s.mergeSliceToSlice(inVal, outVal)
+	}
+
+	if inVal.Kind() == reflect.Slice && inVal.IsNil() {
+		outVal.SetZero()
+		return nil
+	}
+
+	outSlice := reflect.MakeSlice(outVal.Type(), inVal.Len(), inVal.Len())
+
+	if s.opts.Parallelism > 1 && inVal.Len() >= parallelThreshold {
+		if err := s.parseElementsParallel(inVal, outSlice); err != nil {
+			return err
+		}
+
+		outVal.Set(outSlice)
+		return nil
+	}
+
+	var errs []error
+	for i := 0; i < inVal.Len(); i++ {
+		elem := outSlice.Index(i)
+		if err := s.parseIndex(i, inVal.Index(i), elem); err != nil {
+			if !s.opts.CollectErrors {
+				return elementError(i, err)
+			}
+
+			errs = append(errs, elementError(i, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return joinErrors(errs)
+	}
+
+	outVal.Set(outSlice)
+	return nil
+}
+
+// mergeSliceToSlice parses each input element over the existing element at
+// the same index, growing the slice when the input is longer
+func (s *state) mergeSliceToSlice(inVal reflect.Value, outVal reflect.Value) error {
+	n := max(inVal.Len(), outVal.Len())
+	outSlice := reflect.MakeSlice(outVal.Type(), n, n)
+	reflect.Copy(outSlice, outVal)
+
+	var errs []error
+	for i := 0; i < inVal.Len(); i++ {
+		if err := s.parseIndex(i, inVal.Index(i), outSlice.Index(i)); err != nil {
+			if !s.opts.CollectErrors {
+				return elementError(i, err)
+			}
+
+			errs = append(errs, elementError(i, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return joinErrors(errs)
+	}
+
+	outVal.Set(outSlice)
+	return nil
+}
+
+func (s *state) parseSliceToArray(inVal reflect.Value, outVal reflect.Value) error {
+	if !isList(inVal.Kind()) {
+		panic("inVal must be slice or array")
+	}
+
+	if outVal.Kind() != reflect.Array {
+		panic("outVal must be array")
+	}
+
+	inLen := inVal.Len()
+	outLen := outVal.Len()
+
+	// Check if the input slice is longer than the output array
+	if inLen > outLen && !s.opts.TruncateArrays {
+		return fmt.Errorf("%w: input slice (length %d) is longer than output array (length %d)", ErrOverflow, inLen, outLen)
+	}
+
+	// Copy elements from the input slice to the output array
+	var errs []error
+	for i := 0; i < min(inLen, outLen); i++ {
+		if err := s.parseIndex(i, inVal.Index(i), outVal.Index(i)); err != nil {
+			if !s.opts.CollectErrors {
+				return elementError(i, err)
+			}
+
+			errs = append(errs, elementError(i, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return joinErrors(errs)
+	}
+
+	// Elements past the end of a short input are reset to their zero value
+	// and then given the chance to apply their default, or kept when merging
+	if s.opts.Merge {
+		return nil
+	}
+
+	for i := inLen; i < outLen; i++ {
+		elem := outVal.Index(i)
+		elem.Set(reflect.Zero(elem.Type()))
+
+		if defaultable, ok := elem.Addr().Interface().(SetDefault); ok {
+			defaultable.SetDefault()
+		}
+	}
+
+	return nil
+}
+
+func isNumber(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int,
+		reflect.Int8,
+		reflect.Int16,
+		reflect.Int32,
+		reflect.Int64,
+		reflect.Uint,
+		reflect.Uint8,
+		reflect.Uint16,
+		reflect.Uint32,
+		reflect.Uint64,
+		reflect.Float32,
+		reflect.Float64:
+		return true
+	default:
+		return false
+	}
+}
+
+func isBool(kind reflect.Kind) bool {
+	return kind == reflect.Bool
+}
+
+// convertSlice converts a slice whose elements are all of a kind accepted by
+// accept into a []T. Reports false if any element is rejected, and an error
+// for floats that do not fit an integer T.
+func convertSlice[T any](inVal reflect.Value, accept func(reflect.Kind) bool) ([]T, bool, error) {
+	if v, ok := inVal.Interface().([]T); ok {
+		return v, true, nil
+	}
+
+	outType := reflect.TypeFor[T]()
+	out := make([]T, inVal.Len())
+
+	for i := range out {
+		elem := inVal.Index(i)
+		if elem.Kind() == reflect.Interface {
+			elem = elem.Elem()
+		}
+
+		if n, ok := elem.Interface().(json.Number); ok && accept(reflect.Float64) {
+			if i, err := n.Int64(); err == nil {
+				elem = reflect.ValueOf(i)
+			} else if f, err := n.Float64(); err == nil {
+				elem = reflect.ValueOf(f)
+			}
+		}
+
+		if !elem.IsValid() || !accept(elem.Kind()) {
+			return nil, false, nil
+		}
+
+		if err := checkInteger(elem, outType); err != nil {
+			return nil, true, elementError(i, err)
+		}
+
+		out[i] = elem.Convert(outType).Interface().(T)
+	}
+
+	return out, true, nil
+}
+
+// checkInteger rejects a float inVal that is out of range for the integer
+// outType or has a fraction that converting would drop
+func checkInteger(inVal reflect.Value, outType reflect.Type) error {
+	if !inVal.CanFloat() || isFloat(outType.Kind()) {
+		return nil
+	}
+
+	if overflows(inVal, outType) {
+		return fmt.Errorf("%w: value %v of type %s does not fit in %s", ErrOverflow, inVal.Interface(), inVal.Type(), outType)
+	}
+
+	if narrows(inVal, inVal.Convert(outType)) {
+		return fmt.Errorf("%w: value %v of type %s would be narrowed converting to %s", ErrNarrowing, inVal.Interface(), inVal.Type(), outType)
+	}
+
+	return nil
+}
+
+// isList reports whether values of kind k are parsed element by element
+func isList(k reflect.Kind) bool {
+	return k == reflect.Slice || k == reflect.Array
+}
+
+// anySlice returns the elements of any slice as a []any
+func anySlice(inVal reflect.Value) []any {
+	if v, ok := inVal.Interface().([]any); ok {
+		return v
+	}
+
+	out := make([]any, inVal.Len())
+	for i := range out {
+		out[i] = inVal.Index(i).Interface()
+	}
+
+	return out
+}
+
+// Parse slice input to slice output
+func (s *state) parseSlice(inVal reflect.Value, outVal reflect.Value) error {
+	if !isList(inVal.Kind()) {
+		panic("inVal must be slice or array")
+	}
+
+	if s.opts.WeaklyTypedInput {
+		if ok, err := s.parseByteString(inVal, outVal); ok {
+			return err
+		}
+	}
+
+	if parser, ok := outVal.Addr().Interface().(ParseStringSlice); ok {
+		if v, ok, err := convertSlice[string](inVal, isString); ok {
+			if err != nil {
+				return err
+			}
+
+			if s.normalizes() {
+				v = s.normalizeStrings(v)
+			}
+
+			s.trace("ParseStringSlice", inVal, outVal)
+			return parser.ParseStringSlice(v)
+		}
+	}
+
+	if parser, ok := outVal.Addr().Interface().(ParseIntSlice); ok {
+		if v, ok, err := convertSlice[int](inVal, isNumber); ok {
+			if err != nil {
+				return err
+			}
+
+			s.trace("ParseIntSlice", inVal, outVal)
+			return parser.ParseIntSlice(v)
+		}
+	}
+
+	if parser, ok := outVal.Addr().Interface().(ParseInt64Slice); ok {
+		if v, ok, err := convertSlice[int64](inVal, isNumber); ok {
+			if err != nil {
+				return err
+			}
+
+			s.trace("ParseInt64Slice", inVal, outVal)
+			return parser.ParseInt64Slice(v)
+		}
+	}
+
+	if parser, ok := outVal.Addr().Interface().(ParseFloat64Slice); ok {
+		if v, ok, err := convertSlice[float64](inVal, isNumber); ok {
+			if err != nil {
+				return err
+			}
+
+			s.trace("ParseFloat64Slice", inVal, outVal)
+			return parser.ParseFloat64Slice(v)
+		}
+	}
+
+	if parser, ok := outVal.Addr().Interface().(ParseBoolSlice); ok {
+		if v, ok, err := convertSlice[bool](inVal, isBool); ok {
+			if err != nil {
+				return err
+			}
+
+			s.trace("ParseBoolSlice", inVal, outVal)
+			return parser.ParseBoolSlice(v)
+		}
+	}
+
+	if parser, ok := outVal.Addr().Interface().(ParseSlice); ok {
+		s.trace("ParseSlice", inVal, outVal)
+		if s.normalizes() {
+			return parser.ParseSlice(s.normalizeAll(anySlice(inVal)).([]any))
+		}
+
+		return parser.ParseSlice(anySlice(inVal))
+	}
+
+	if outVal.Kind() == reflect.Slice {
+		if ok, err := s.parseNumbers(inVal, outVal); ok {
+			return err
+		}
+
+		return s.parseSliceToSlice(inVal, outVal)
+	}
+
+	if outVal.Kind() == reflect.Array {
+		return s.parseSliceToArray(inVal, outVal)
+	}
+
+	// A single element is parsed on its own into other outputs, such as the
+	// only value of a header into a string field
+	if (s.opts.unwrapSingle || s.opts.WeaklyTypedInput) && inVal.Len() == 1 {
+		return s.parseIndex(0, inVal.Index(0), outVal)
 	}
 
-	return fmt.Errorf("inVal %s is not parseable to outVal %s", inVal.Type(), outVal.Type())
+	return mismatch(inVal, outVal)
 }
@@ -1,10 +1,17 @@
 package kaeru
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"math"
 	"reflect"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -45,6 +52,10 @@ func (ca *CreatedAt) ParseString(s string) error {
 	return nil
 }
 
+func (ca CreatedAt) String() string {
+	return ca.Time.Format(time.RFC3339)
+}
+
 func (e *Email) ParseString(s string) error {
 	if !strings.Contains(s, "@") {
 		return errors.New("Email must contain an @ symbol")
@@ -203,3 +214,2809 @@ func TestParsePost(t *testing.T) {
 func ptr(s string) *string {
 	return &s
 }
+
+type nested []nested
+
+func nestedInput(depth int) any {
+	var v any = []any{}
+	for i := 0; i < depth; i++ {
+		v = []any{v}
+	}
+	return v
+}
+
+func TestParseMaxDepth(t *testing.T) {
+	input := nestedInput(20)
+
+	if err := Parse(input, new(nested)); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	p := NewParser(WithMaxDepth(10))
+	err := p.Parse(input, new(nested))
+	if !errors.Is(err, ErrMaxDepthExceeded) || !strings.Contains(err.Error(), "limit is 10") {
+		t.Fatalf("expected max depth error, got: %v", err)
+	}
+}
+
+type treeNode struct {
+	Value int
+	Child *treeNode
+}
+
+func TestParseCycle(t *testing.T) {
+	var input any = map[string]any{"Value": 0}
+	for i := 1; i < 100; i++ {
+		input = map[string]any{"Value": i, "Child": input}
+	}
+
+	if err := Parse(input, new(treeNode)); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	cyclic := map[string]any{"Value": 1}
+	cyclic["Child"] = cyclic
+
+	err := Parse(cyclic, new(treeNode))
+	if !errors.Is(err, ErrCycle) {
+		t.Fatalf("expected cycle error for cyclic input, got: %v", err)
+	}
+
+	node := new(treeNode)
+	node.Child = node
+
+	err = Parse(map[string]any{"Value": 0, "Child": map[string]any{"Value": 1}}, node)
+	if !errors.Is(err, ErrCycle) {
+		t.Fatalf("expected cycle error for cyclic output, got: %v", err)
+	}
+}
+
+func TestParseNestedPointers(t *testing.T) {
+	var s **string
+	if err := Parse("hello", &s); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+	if s == nil || *s == nil || **s != "hello" {
+		t.Fatalf("expected **string to be hello, got %v", s)
+	}
+
+	comments := new(*[]*Comment)
+	err := Parse([]any{
+		map[string]any{
+			"Body":     "A comment that is long enough",
+			"Metadata": map[string]any{},
+			"Upvotes":  1.0,
+			"Commenter": map[string]any{
+				"Username":  "janedoe",
+				"Email":     "jane@example.com",
+				"CreatedAt": "2023-09-10T09:00:00Z",
+				"IsAdmin":   false,
+			},
+		},
+	}, comments)
+	if err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+	if *comments == nil || len(**comments) != 1 || (**comments)[0].Commenter.Username != "janedoe" {
+		t.Fatalf("unexpected *[]*Comment result: %+v", *comments)
+	}
+
+	users := new(*map[string]*User)
+	err = Parse(map[string]any{
+		"john": map[string]any{
+			"Username":  "johndoe",
+			"Email":     "john@example.com",
+			"CreatedAt": "2023-09-11T10:00:00Z",
+			"IsAdmin":   true,
+		},
+	}, users)
+	if err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+	if *users == nil || (**users)["john"] == nil || (**users)["john"].Email != "john@example.com" {
+		t.Fatalf("unexpected *map[string]*User result: %+v", *users)
+	}
+}
+
+type UpdatedAt struct{ time.Time }
+
+func (ua *UpdatedAt) ParseTime(t time.Time) error {
+	if t.Year() < 2000 {
+		return errors.New("UpdatedAt must not be before the year 2000")
+	}
+
+	*ua = UpdatedAt{t.UTC()}
+
+	return nil
+}
+
+type Revision struct {
+	UpdatedAt UpdatedAt
+	Published time.Time
+}
+
+func TestParseTime(t *testing.T) {
+	expected := time.Date(2023, 9, 11, 10, 0, 0, 0, time.UTC)
+
+	inputs := []map[string]any{
+		{"UpdatedAt": expected, "Published": expected},
+		{"UpdatedAt": "2023-09-11T10:00:00Z", "Published": "2023-09-11T10:00:00Z"},
+	}
+
+	for _, input := range inputs {
+		actual := new(Revision)
+		if err := Parse(input, actual); err != nil {
+			t.Fatalf("Parse returned an error: %v", err)
+		}
+
+		if !actual.UpdatedAt.Equal(expected) || !actual.Published.Equal(expected) {
+			t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %v", actual, expected)
+		}
+	}
+
+	err := Parse(map[string]any{"UpdatedAt": time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)}, new(Revision))
+	if err == nil {
+		t.Errorf("expected ParseTime error for a time before 2000")
+	}
+
+	p := NewParser(WithTimeLayout(time.DateOnly))
+	actual := new(Revision)
+	if err := p.Parse(map[string]any{"UpdatedAt": "2023-09-11", "Published": "2023-09-11"}, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	date := time.Date(2023, 9, 11, 0, 0, 0, 0, time.UTC)
+	if !actual.UpdatedAt.Equal(date) || !actual.Published.Equal(date) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %v", actual, date)
+	}
+}
+
+func TestParseTimeLocation(t *testing.T) {
+	const layout = "2006-01-02T15:04:05"
+	input := "2023-09-11T10:00:00"
+	stockholm := time.FixedZone("CEST", 2*60*60)
+
+	tests := []struct {
+		name     string
+		loc      *time.Location
+		expected time.Time
+	}{
+		{"default", nil, time.Date(2023, 9, 11, 10, 0, 0, 0, time.UTC)},
+		{"utc", time.UTC, time.Date(2023, 9, 11, 10, 0, 0, 0, time.UTC)},
+		{"zone", stockholm, time.Date(2023, 9, 11, 8, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		p := NewParser(WithTimeLayout(layout), WithTimeLocation(tt.loc))
+
+		var actual time.Time
+		if err := p.Parse(input, &actual); err != nil {
+			t.Fatalf("%s: Parse returned an error: %v", tt.name, err)
+		}
+
+		if !actual.Equal(tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, actual)
+		}
+
+		if tt.loc != nil && actual.Location() != tt.loc {
+			t.Errorf("%s: expected the time in %v, got %v", tt.name, tt.loc, actual.Location())
+		}
+	}
+
+	// An explicit offset wins over the location
+	var actual time.Time
+	if err := NewParser(WithTimeLocation(stockholm)).Parse("2023-09-11T10:00:00Z", &actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !actual.Equal(time.Date(2023, 9, 11, 10, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the offset of the input to be kept, got %v", actual)
+	}
+}
+
+func TestParseTruncateArrays(t *testing.T) {
+	input := []any{1.0, 2.0, 3.0, 4.0, 5.0}
+
+	if err := Parse(input, new([3]int)); err == nil {
+		t.Fatalf("expected error parsing longer slice into array")
+	}
+
+	actual := new([3]int)
+	if err := NewParser(WithTruncateArrays(true)).Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if *actual != [3]int{1, 2, 3} {
+		t.Errorf("Parse result not as expected.\nGot: %v\nWant: %v", *actual, [3]int{1, 2, 3})
+	}
+}
+
+type Weight int
+
+func (w *Weight) SetDefault() {
+	*w = 1
+}
+
+func TestParseShortArray(t *testing.T) {
+	input := []any{1.0, 2.0, 3.0}
+
+	ints := [5]int{9, 9, 9, 9, 9}
+	if err := Parse(input, &ints); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if ints != [5]int{1, 2, 3, 0, 0} {
+		t.Errorf("Parse result not as expected.\nGot: %v\nWant: %v", ints, [5]int{1, 2, 3, 0, 0})
+	}
+
+	weights := new([5]Weight)
+	if err := Parse(input, weights); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if *weights != [5]Weight{1, 2, 3, 1, 1} {
+		t.Errorf("Parse result not as expected.\nGot: %v\nWant: %v", *weights, [5]Weight{1, 2, 3, 1, 1})
+	}
+}
+
+type Scores []int
+type Vector []float64
+type Flags []bool
+
+func (s *Scores) ParseIntSlice(v []int) error {
+	for _, score := range v {
+		if score < 0 || score > 100 {
+			return errors.New("Scores must be between 0 and 100")
+		}
+	}
+
+	*s = Scores(v)
+
+	return nil
+}
+
+func (v *Vector) ParseFloat64Slice(f []float64) error {
+	if len(f) != 3 {
+		return errors.New("Vector must have exactly 3 components")
+	}
+
+	*v = Vector(f)
+
+	return nil
+}
+
+func (f *Flags) ParseBoolSlice(b []bool) error {
+	*f = Flags(b)
+	return nil
+}
+
+type Measurement struct {
+	Scores Scores
+	Vector Vector
+	Flags  Flags
+}
+
+func TestParseTypedSlices(t *testing.T) {
+	input := map[string]any{
+		"Scores": []any{10.0, 20.0, 30.0},
+		"Vector": []int{1, 2, 3},
+		"Flags":  []any{true, false},
+	}
+
+	expected := &Measurement{
+		Scores: Scores{10, 20, 30},
+		Vector: Vector{1, 2, 3},
+		Flags:  Flags{true, false},
+	}
+
+	actual := new(Measurement)
+	if err := Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	input["Scores"] = []any{10.0, 200.0}
+	if err := Parse(input, new(Measurement)); err == nil {
+		t.Errorf("expected ParseIntSlice validation error")
+	}
+
+	for _, scores := range []struct {
+		input []any
+		err   error
+	}{
+		{[]any{10.0, 1.5}, ErrNarrowing},
+		{[]any{1e30}, ErrOverflow},
+		{[]any{-2.0, math.Inf(1)}, ErrOverflow},
+	} {
+		input["Scores"] = scores.input
+		if err := Parse(input, new(Measurement)); !errors.Is(err, scores.err) {
+			t.Errorf("expected %v for scores %v, got: %v", scores.err, scores.input, err)
+		}
+	}
+}
+
+type Contact struct {
+	Email Email `parse:"email,alias=mail,alias=e_mail"`
+}
+
+func TestParseAliases(t *testing.T) {
+	cases := []struct {
+		input    map[string]any
+		expected Email
+	}{
+		{map[string]any{"email": "a@example.com"}, "a@example.com"},
+		{map[string]any{"mail": "b@example.com"}, "b@example.com"},
+		{map[string]any{"e_mail": "c@example.com"}, "c@example.com"},
+		{map[string]any{"email": "a@example.com", "mail": "b@example.com"}, "a@example.com"},
+		{map[string]any{"e_mail": "c@example.com", "mail": "b@example.com"}, "b@example.com"},
+	}
+
+	for _, c := range cases {
+		actual := new(Contact)
+		if err := Parse(c.input, actual); err != nil {
+			t.Fatalf("Parse returned an error: %v", err)
+		}
+
+		if actual.Email != c.expected {
+			t.Errorf("Parse of %v not as expected.\nGot: %s\nWant: %s", c.input, actual.Email, c.expected)
+		}
+	}
+}
+
+func TestParseDisallowDuplicateKeys(t *testing.T) {
+	strict := NewParser(WithDisallowDuplicateKeys(true))
+
+	actual := new(Contact)
+	if err := strict.Parse(map[string]any{"mail": "b@example.com"}, actual); err != nil || actual.Email != "b@example.com" {
+		t.Errorf("expected a single alias to parse, got: %q, %v", actual.Email, err)
+	}
+
+	err := strict.Parse(map[string]any{"email": "a@example.com", "e_mail": "c@example.com"}, new(Contact))
+	if !errors.Is(err, ErrDuplicateKey) {
+		t.Fatalf("expected duplicate key error, got: %v", err)
+	}
+
+	if !strings.Contains(err.Error(), "email") || !strings.Contains(err.Error(), "e_mail") {
+		t.Errorf("expected error to name both keys, got: %v", err)
+	}
+}
+
+func TestParseKeyTransform(t *testing.T) {
+	stripPrefix := func(key string) string {
+		return strings.TrimPrefix(key, "cfg_")
+	}
+
+	p := NewParser(WithKeyTransform(stripPrefix))
+
+	actual := new(Server)
+	if err := p.Parse(map[string]any{"cfg_Host": "localhost", "Port": 8080}, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if *actual != (Server{"localhost", 8080}) {
+		t.Errorf("Parse result not as expected, got: %+v", *actual)
+	}
+
+	contact := new(Contact)
+	if err := p.Parse(map[string]any{"cfg_mail": "b@example.com"}, contact); err != nil || contact.Email != "b@example.com" {
+		t.Errorf("expected aliases to match transformed keys, got: %q, %v", contact.Email, err)
+	}
+
+	input := map[string]any{"Host": "a", "cfg_Host": "b", "Port": 1}
+	if err := p.Parse(input, actual); err != nil || actual.Host != "a" {
+		t.Errorf("expected the smallest original key to win, got: %q, %v", actual.Host, err)
+	}
+
+	strict := NewParser(WithKeyTransform(stripPrefix), WithDisallowDuplicateKeys(true))
+	if err := strict.Parse(input, new(Server)); !errors.Is(err, ErrDuplicateKey) {
+		t.Errorf("expected duplicate key error, got: %v", err)
+	}
+}
+
+type Hostname string
+
+func (h *Hostname) ParseContextual(ctx context.Context, v any) error {
+	s, ok := v.(string)
+	if !ok {
+		return errors.New("Hostname must be a string")
+	}
+
+	// Stands in for a lookup that honors the deadline
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(time.Millisecond):
+	}
+
+	*h = Hostname(s)
+
+	return nil
+}
+
+func TestParseContext(t *testing.T) {
+	input := []any{"a.example.com", "b.example.com"}
+
+	actual := new([]Hostname)
+	if err := ParseContext(context.Background(), input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(*actual, []Hostname{"a.example.com", "b.example.com"}) {
+		t.Errorf("Parse result not as expected.\nGot: %v", *actual)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := ParseContext(ctx, input, new([]Hostname)); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got: %v", err)
+	}
+
+	if err := ParseContext(ctx, []any{1.0}, new([]int)); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got: %v", err)
+	}
+}
+
+type Setting string
+
+func (k *Setting) ParseMapKey(key string) error {
+	if key != strings.ToLower(key) {
+		return errors.New("Setting keys must be lowercase")
+	}
+
+	*k = Setting(key)
+
+	return nil
+}
+
+func TestParseMapKeys(t *testing.T) {
+	emails := map[Username]Email{}
+	if err := Parse(map[string]any{"johndoe": "john@example.com"}, &emails); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if emails["johndoe"] != "john@example.com" {
+		t.Errorf("Parse result not as expected.\nGot: %v", emails)
+	}
+
+	if err := Parse(map[string]any{"!": "john@example.com"}, &emails); err == nil {
+		t.Errorf("expected ParseString error for invalid key")
+	}
+
+	settings := map[Setting]string{}
+	if err := Parse(map[string]any{"theme": "dark"}, &settings); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if settings["theme"] != "dark" {
+		t.Errorf("Parse result not as expected.\nGot: %v", settings)
+	}
+
+	if err := Parse(map[string]any{"Theme": "dark"}, &settings); err == nil {
+		t.Errorf("expected ParseMapKey error for invalid key")
+	}
+}
+
+func TestParseMultiDimensionalSlices(t *testing.T) {
+	matrix := new([][]int)
+	if err := Parse([]any{[]any{1.0, 2.0}, []any{3.0}, []any{}}, matrix); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(*matrix, [][]int{{1, 2}, {3}, {}}) {
+		t.Errorf("Parse result not as expected.\nGot: %v", *matrix)
+	}
+
+	cube := new([][][]Label)
+	if err := Parse([][]any{{[]any{"a", "b"}}, {[]string{"c"}}}, cube); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(*cube, [][][]Label{{{"a", "b"}}, {{"c"}}}) {
+		t.Errorf("Parse result not as expected.\nGot: %v", *cube)
+	}
+
+	series := new([]map[string][]float64)
+	input := []any{
+		map[string]any{"x": []any{1.0, 2.0}, "y": []any{3.0}},
+		map[string]any{},
+	}
+	if err := Parse(input, series); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := []map[string][]float64{{"x": {1, 2}, "y": {3}}, {}}
+	if !reflect.DeepEqual(*series, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %v\nWant: %v", *series, expected)
+	}
+
+	if err := Parse([]any{[]any{1.0}, []any{"x"}}, new([][]int)); err == nil {
+		t.Errorf("expected error for non numeric inner element")
+	}
+}
+
+type SnowflakeID int64
+
+func (id *SnowflakeID) ParseInt64(i int64) error {
+	if i <= 0 {
+		return errors.New("SnowflakeID must be positive")
+	}
+
+	*id = SnowflakeID(i)
+
+	return nil
+}
+
+type Tweet struct {
+	ID      SnowflakeID
+	Raw     int64
+	Count   uint64
+	Score   float64
+	Upvotes Upvotes
+	Text    Title
+}
+
+func TestParseJsonNumber(t *testing.T) {
+	input := map[string]any{
+		"ID":      json.Number("9007199254740993"),
+		"Raw":     json.Number("-9007199254740993"),
+		"Count":   json.Number("18446744073709551615"),
+		"Score":   json.Number("1.5"),
+		"Upvotes": json.Number("42"),
+		"Text":    json.Number("123"),
+	}
+
+	expected := &Tweet{
+		ID:      9007199254740993,
+		Raw:     -9007199254740993,
+		Count:   18446744073709551615,
+		Score:   1.5,
+		Upvotes: 42,
+		Text:    "123",
+	}
+
+	actual := new(Tweet)
+	if err := Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	input["ID"] = json.Number("-1")
+	if err := Parse(input, new(Tweet)); err == nil {
+		t.Errorf("expected ParseInt64 error for negative id")
+	}
+
+	var text string
+	if err := Parse(json.Number("123"), &text); err != nil || text != "123" {
+		t.Errorf("expected a number into a string to keep its text, got: %q, %v", text, err)
+	}
+
+	var named struct{ Name string }
+	if err := NewParser(WithUseNumber(true)).ParseJsonBytes([]byte(`{"Name": 65}`), &named); err != nil || named.Name != "65" {
+		t.Errorf("expected a number into a string to keep its text, got: %q, %v", named.Name, err)
+	}
+}
+
+func TestParseJsonPrecise(t *testing.T) {
+	data := []byte(`{"ID": 9007199254740993, "Raw": 9007199254740993, "Count": 1, "Score": 0.5, "Upvotes": 3, "Text": "hello"}`)
+	parser := NewParser(WithUseNumber(true))
+
+	actual := new(Tweet)
+	if err := parser.ParseJsonBytes(data, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.ID != 9007199254740993 || actual.Raw != 9007199254740993 {
+		t.Errorf("large integers lost precision, got ID %d and Raw %d", actual.ID, actual.Raw)
+	}
+
+	if actual.Score != 0.5 || actual.Upvotes != 3 {
+		t.Errorf("Parse result not as expected.\nGot: %+v", actual)
+	}
+
+	actual = new(Tweet)
+	if err := parser.ParseJson(bytes.NewReader(data), actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Raw != 9007199254740993 {
+		t.Errorf("large integers lost precision, got Raw %d", actual.Raw)
+	}
+
+	vector := new(Vector)
+	if err := parser.ParseJsonBytes([]byte(`[1, 2.5, 3]`), vector); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(*vector, Vector{1, 2.5, 3}) {
+		t.Errorf("Parse result not as expected.\nGot: %v", *vector)
+	}
+
+	if err := parser.ParseJsonBytes([]byte(`{} x`), new(map[string]any)); err == nil {
+		t.Errorf("expected error for trailing data")
+	}
+}
+
+type Role string
+
+type Member struct {
+	Role   Role    `parse:"role,enum=admin|user|guest"`
+	Status *string `parse:"status,enum=active|banned"`
+}
+
+func TestParseEnum(t *testing.T) {
+	actual := new(Member)
+	if err := Parse(map[string]any{"role": "admin", "status": "active"}, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Role != "admin" || actual.Status == nil || *actual.Status != "active" {
+		t.Errorf("Parse result not as expected.\nGot: %+v", actual)
+	}
+
+	err := Parse(map[string]any{"role": "owner"}, new(Member))
+	if err == nil || !strings.Contains(err.Error(), "must be one of admin, user, guest") {
+		t.Errorf("expected enum error, got: %v", err)
+	}
+
+	err = Parse(map[string]any{"role": 1.0}, new(Member))
+	if err == nil || !strings.Contains(err.Error(), "must be one of") {
+		t.Errorf("expected enum error for non string input, got: %v", err)
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	post := new(Post)
+	field := reflect.ValueOf(post).Elem().FieldByName("Labels")
+
+	if err := ParseValue([]any{"new", "featured"}, field); err != nil {
+		t.Fatalf("ParseValue returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(post.Labels, []Label{"new", "featured"}) {
+		t.Errorf("ParseValue result not as expected.\nGot: %v", post.Labels)
+	}
+
+	err := ParseValue("Title", reflect.ValueOf(*post).FieldByName("Title"))
+	if err == nil || !strings.Contains(err.Error(), "not settable") {
+		t.Errorf("expected not settable error, got: %v", err)
+	}
+}
+
+type Plugin struct {
+	Name  string         `parse:"name"`
+	Email Email          `parse:"email,alias=mail"`
+	Extra map[string]any `parse:",remain"`
+}
+
+type DoubleRemain struct {
+	A map[string]any `parse:",remain"`
+	B map[string]any `parse:",remain"`
+}
+
+func TestParseRemain(t *testing.T) {
+	input := map[string]any{
+		"name":    "cache",
+		"mail":    "ops@example.com",
+		"ttl":     30.0,
+		"enabled": true,
+	}
+
+	expected := &Plugin{
+		Name:  "cache",
+		Email: "ops@example.com",
+		Extra: map[string]any{"ttl": 30.0, "enabled": true},
+	}
+
+	actual := new(Plugin)
+	if err := Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	actual = new(Plugin)
+	if err := Parse(map[string]any{"name": "cache", "email": "ops@example.com"}, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Extra != nil {
+		t.Errorf("expected no remain map without leftover keys, got: %v", actual.Extra)
+	}
+
+	err := Parse(map[string]any{}, new(DoubleRemain))
+	if !errors.Is(err, ErrUnsupportedKind) || !strings.Contains(err.Error(), "only one remain field") {
+		t.Errorf("expected error for two remain fields, got: %v", err)
+	}
+}
+
+type Manifest struct {
+	Name       string            `parse:"name"`
+	Version    int               `parse:"version"`
+	Extensions map[string]string `parse:",inline"`
+}
+
+func TestParseInline(t *testing.T) {
+	input := map[string]any{
+		"name":      "api",
+		"version":   2.0,
+		"x-owner":   "platform",
+		"x-runtime": "go",
+	}
+
+	actual := &Manifest{Extensions: map[string]string{"x-tier": "gold", "x-owner": "unknown"}}
+	if err := Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := &Manifest{
+		Name:       "api",
+		Version:    2,
+		Extensions: map[string]string{"x-tier": "gold", "x-owner": "platform", "x-runtime": "go"},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected the leftover keys to be merged into the inline map.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	var unknown []string
+	p := NewParser(WithDisallowUnknownKeys(true), WithOnUnknownKey(func(path string, value any) {
+		unknown = append(unknown, path)
+	}))
+	if err := p.Parse(input, new(Manifest)); err != nil || len(unknown) > 0 {
+		t.Errorf("expected keys absorbed by the inline map to be known, got: %v, %v", unknown, err)
+	}
+
+	tree, err := Unparse(expected)
+	if err != nil {
+		t.Fatalf("Unparse returned an error: %v", err)
+	}
+
+	expectedTree := map[string]any{
+		"name":      "api",
+		"version":   int64(2),
+		"x-tier":    "gold",
+		"x-owner":   "platform",
+		"x-runtime": "go",
+	}
+
+	if !reflect.DeepEqual(tree, expectedTree) {
+		t.Errorf("expected Unparse to flatten the inline map.\nGot: %+v\nWant: %+v", tree, expectedTree)
+	}
+
+	type Mixed struct {
+		Extensions map[string]any `parse:",inline"`
+		Rest       map[string]any `parse:",remain"`
+	}
+
+	if err := Parse(map[string]any{}, new(Mixed)); err == nil || !strings.Contains(err.Error(), "only one remain field") {
+		t.Errorf("expected an inline map and a remain field together to be rejected, got: %v", err)
+	}
+
+	type Scalar struct {
+		Extensions string `parse:",inline"`
+	}
+
+	if err := Parse(map[string]any{"a": "b"}, new(Scalar)); !errors.Is(err, ErrUnsupportedKind) {
+		t.Errorf("expected an inline field that is not a map to be rejected, got: %v", err)
+	}
+}
+
+type Headers map[string]string
+
+func (h *Headers) ParseStringMap(m map[string]string) error {
+	for key := range m {
+		if key == "" {
+			return errors.New("Headers must not contain empty keys")
+		}
+	}
+
+	*h = Headers(m)
+
+	return nil
+}
+
+func TestParseStringMapFlavors(t *testing.T) {
+	expected := Headers{"accept": "text/html"}
+
+	inputs := []any{
+		map[string]string{"accept": "text/html"},
+		Metadata{"accept": "text/html"},
+		map[Label]Title{"accept": "text/html"},
+		map[string]any{"accept": "text/html"},
+	}
+
+	for _, input := range inputs {
+		actual := new(Headers)
+		if err := Parse(input, actual); err != nil {
+			t.Fatalf("Parse of %T returned an error: %v", input, err)
+		}
+
+		if !reflect.DeepEqual(*actual, expected) {
+			t.Errorf("Parse of %T not as expected.\nGot: %v\nWant: %v", input, *actual, expected)
+		}
+	}
+
+	if err := Parse(Metadata{"": "text/html"}, new(Headers)); err == nil {
+		t.Errorf("expected ParseStringMap error for empty key")
+	}
+}
+
+type FormValues map[string][]string
+
+func (f *FormValues) ParseStringSliceMap(m map[string][]string) error {
+	for key, values := range m {
+		if len(values) == 0 {
+			return fmt.Errorf("FormValues %s must have at least one value", key)
+		}
+	}
+
+	*f = FormValues(m)
+
+	return nil
+}
+
+type Values map[string][]string
+
+func TestParseStringSliceMap(t *testing.T) {
+	expected := FormValues{"tag": {"go", "parsing"}}
+
+	inputs := []any{
+		map[string][]string{"tag": {"go", "parsing"}},
+		Values{"tag": {"go", "parsing"}},
+		map[Label][]Title{"tag": {"go", "parsing"}},
+		map[string]any{"tag": []any{"go", "parsing"}},
+	}
+
+	for _, input := range inputs {
+		actual := new(FormValues)
+		if err := Parse(input, actual); err != nil {
+			t.Fatalf("Parse of %T returned an error: %v", input, err)
+		}
+
+		if !reflect.DeepEqual(*actual, expected) {
+			t.Errorf("Parse of %T not as expected.\nGot: %v\nWant: %v", input, *actual, expected)
+		}
+	}
+
+	if err := Parse(map[string]any{"tag": []any{}}, new(FormValues)); err == nil {
+		t.Errorf("expected ParseStringSliceMap error for empty values")
+	}
+
+	if err := Parse(map[string]any{"tag": []any{1.0}}, new(FormValues)); err == nil {
+		t.Errorf("expected non string values not to dispatch to ParseStringSliceMap")
+	}
+}
+
+type Histogram map[int]float64
+
+func (h *Histogram) ParseMapReflect(m reflect.Value) error {
+	*h = Histogram{}
+
+	iter := m.MapRange()
+	for iter.Next() {
+		key, value := iter.Key(), iter.Value()
+		if !key.CanInt() {
+			return fmt.Errorf("Histogram keys must be integers, got %s", key.Type())
+		}
+
+		if value.Kind() != reflect.Slice {
+			return fmt.Errorf("Histogram values must be slices, got %s", value.Type())
+		}
+
+		sum := 0.0
+		for i := 0; i < value.Len(); i++ {
+			sum += value.Index(i).Float()
+		}
+
+		(*h)[int(key.Int())] = sum
+	}
+
+	return nil
+}
+
+type Weights map[string]float64
+
+func (w *Weights) ParseMap(m map[string]any) error {
+	*w = Weights{"parse_map": float64(len(m))}
+	return nil
+}
+
+func (w *Weights) ParseMapReflect(m reflect.Value) error {
+	*w = Weights{"parse_map_reflect": float64(m.Len())}
+	return nil
+}
+
+func TestParseMapReflect(t *testing.T) {
+	actual := new(Histogram)
+	if err := Parse(map[int][]float64{1: {0.5, 0.5}, 2: {3}}, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(*actual, Histogram{1: 1, 2: 3}) {
+		t.Errorf("Parse result not as expected, got: %v", *actual)
+	}
+
+	if err := Parse(map[string][]float64{"a": {1}}, actual); err == nil {
+		t.Errorf("expected ParseMapReflect error for string keys")
+	}
+
+	weights := new(Weights)
+	if err := Parse(map[string]any{"a": 1.0}, weights); err != nil || (*weights)["parse_map"] != 1 {
+		t.Errorf("expected ParseMap to be preferred, got: %v, %v", *weights, err)
+	}
+
+	if err := Parse(map[int]any{1: 1.0}, weights); err != nil || (*weights)["parse_map_reflect"] != 1 {
+		t.Errorf("expected ParseMapReflect for non string keys, got: %v, %v", *weights, err)
+	}
+}
+
+type Server struct {
+	Host string
+	Port int
+}
+
+type Config struct {
+	Name     string
+	Servers  []Server
+	Settings map[string]any
+	Labels   map[string]Label
+}
+
+func TestParseMerge(t *testing.T) {
+	parser := NewParser(WithMerge(true))
+
+	base := map[string]any{
+		"Name":     "base",
+		"Servers":  []any{map[string]any{"Host": "a", "Port": 80.0}},
+		"Settings": map[string]any{"debug": false, "nested": map[string]any{"x": 1.0}},
+		"Labels":   map[string]any{"env": "prod"},
+	}
+
+	override := map[string]any{
+		"Servers": []any{
+			map[string]any{"Port": 8080.0},
+			map[string]any{"Host": "b", "Port": 81.0},
+		},
+		"Settings": map[string]any{"debug": true},
+		"Labels":   map[string]any{"team": "core"},
+	}
+
+	expected := &Config{
+		Name:     "base",
+		Servers:  []Server{{"a", 8080}, {"b", 81}},
+		Settings: map[string]any{"debug": true, "nested": map[string]any{"x": 1.0}},
+		Labels:   map[string]Label{"env": "prod", "team": "core"},
+	}
+
+	actual := new(Config)
+	if err := parser.Parse(base, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	baseSettings := actual.Settings
+
+	if err := parser.Parse(override, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	if baseSettings["debug"] != false {
+		t.Errorf("merging must not modify the previous map in place")
+	}
+
+	// Without merging the second parse replaces everything and fails on the
+	// missing required Name
+	if err := Parse(override, new(Config)); err == nil {
+		t.Errorf("expected error for missing Name without merge")
+	}
+}
+
+func TestParseDisallowTypeNarrowing(t *testing.T) {
+	strict := NewParser(WithDisallowTypeNarrowing(true))
+
+	// Negative values into unsigned outputs are rejected even when
+	// narrowing is allowed
+	lossy := []struct {
+		input      any
+		output     any
+		permissive error
+		err        error
+	}{
+		{42.9, new(int), nil, ErrNarrowing},
+		{300.0, new(int8), nil, ErrOverflow},
+		{int64(70000), new(uint16), nil, ErrOverflow},
+		{-1.0, new(uint), ErrOverflow, ErrOverflow},
+		{int64(65), new(string), nil, ErrNarrowing},
+	}
+
+	for _, c := range lossy {
+		if err := Parse(c.input, c.output); !errors.Is(err, c.permissive) {
+			t.Errorf("expected permissive Parse of %v into %T to return %v, got: %v", c.input, c.output, c.permissive, err)
+		}
+
+		if err := strict.Parse(c.input, c.output); !errors.Is(err, c.err) {
+			t.Errorf("expected %v for %v into %T, got: %v", c.err, c.input, c.output, err)
+		}
+	}
+
+	exact := []struct {
+		input  any
+		output any
+	}{
+		{42.0, new(int)},
+		{127.0, new(int8)},
+		{0.1, new(float32)},
+		{true, new(IsAdmin)},
+	}
+
+	for _, c := range exact {
+		if err := strict.Parse(c.input, c.output); err != nil {
+			t.Errorf("strict Parse of %v into %T returned an error: %v", c.input, c.output, err)
+		}
+	}
+}
+
+func TestParseCoerceToString(t *testing.T) {
+	coercing := NewParser(WithCoerceToString(true))
+
+	var username Username
+	if err := coercing.Parse(123456, &username); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if username != "123456" {
+		t.Errorf("expected coerced username 123456, got: %q", username)
+	}
+
+	if err := coercing.Parse(true, &username); err != nil || username != "true" {
+		t.Errorf("expected coerced username true, got: %q, %v", username, err)
+	}
+
+	if err := coercing.Parse(12, &username); err == nil {
+		t.Errorf("expected ParseString validation error for coerced input")
+	}
+
+	var upvotes Upvotes
+	if err := coercing.Parse(7, &upvotes); err != nil || upvotes != 7 {
+		t.Errorf("expected numeric parser to be preferred, got: %d, %v", upvotes, err)
+	}
+
+	username = ""
+	if err := Parse(123456, &username); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if username == "123456" {
+		t.Errorf("expected ParseString not to be called without CoerceToString")
+	}
+}
+
+type Tags []string
+
+func (t *Tags) ParseStringSlice(s []string) error {
+	if len(s) > 3 {
+		return errors.New("Tags must have at most 3 entries")
+	}
+
+	*t = Tags(s)
+
+	return nil
+}
+
+func TestParseStringSliceFromAny(t *testing.T) {
+	inputs := []any{
+		[]string{"new", "featured"},
+		[]any{"new", "featured"},
+		[]Label{"new", "featured"},
+		[2]Label{"new", "featured"},
+	}
+
+	for _, input := range inputs {
+		actual := new(Tags)
+		if err := Parse(input, actual); err != nil {
+			t.Fatalf("Parse returned an error: %v", err)
+		}
+
+		if !reflect.DeepEqual(*actual, Tags{"new", "featured"}) {
+			t.Errorf("Parse of %T not as expected.\nGot: %v", input, *actual)
+		}
+	}
+
+	if err := Parse([]any{"a", "b", "c", "d"}, new(Tags)); err == nil {
+		t.Errorf("expected ParseStringSlice error for too many tags")
+	}
+
+	if err := Parse([]Label{"a", "b", "c", "d"}, new(Tags)); err == nil {
+		t.Errorf("expected a slice of named strings to reach ParseStringSlice")
+	}
+
+	// Mixed element types skip the bulk interface and parse element by element
+	actual := new(Tags)
+	if err := Parse([]any{"a", 1.0}, actual); err == nil {
+		t.Errorf("expected error for non string element, got %v", *actual)
+	}
+}
+
+type Retries int
+
+func (r *Retries) SetDefault() {
+	*r = 3
+}
+
+type Timeout float64
+
+func (t *Timeout) SetDefault() {
+	*t = 30
+}
+
+type HTTPConfig struct {
+	Retries Retries
+	Timeout Timeout
+}
+
+type ClientConfig struct {
+	Name string
+	HTTP HTTPConfig
+}
+
+type StrictClientConfig struct {
+	HTTP  HTTPConfig
+	Owner User
+}
+
+func TestParseNestedDefaults(t *testing.T) {
+	actual := new(ClientConfig)
+	if err := Parse(map[string]any{"Name": "api"}, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := &ClientConfig{Name: "api", HTTP: HTTPConfig{Retries: 3, Timeout: 30}}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	actual = new(ClientConfig)
+	if err := Parse(map[string]any{"Name": "api", "HTTP": map[string]any{"Retries": 5.0}}, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected.HTTP.Retries = 5
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	// A missing section is still an error when it has fields without defaults
+	err := Parse(map[string]any{}, new(StrictClientConfig))
+	if err == nil || !strings.Contains(err.Error(), "error parsing field Owner: error parsing field Username") {
+		t.Errorf("expected missing Owner.Username error, got: %v", err)
+	}
+}
+
+func TestParseSentinelErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		err    error
+		target error
+	}{
+		{"not a pointer", Parse(map[string]any{}, Post{}), ErrOutputNotPointer},
+		{"nil pointer", Parse(map[string]any{}, (*Post)(nil)), ErrNilOutput},
+		{"missing required", Parse(map[string]any{}, new(User)), ErrRequiredMissing},
+		{"type mismatch", Parse("text", new(int)), ErrTypeMismatch},
+		{"unsupported kind", Parse(complex(1, 2), new(int)), ErrUnsupportedKind},
+		{"array overflow", Parse([]any{1.0, 2.0}, new([1]int)), ErrOverflow},
+		{"not settable", ParseValue(1.0, reflect.ValueOf(0)), ErrOutputNotSettable},
+	}
+
+	for _, c := range cases {
+		if !errors.Is(c.err, c.target) {
+			t.Errorf("%s: expected %v, got: %v", c.name, c.target, c.err)
+		}
+	}
+}
+
+type Describer interface {
+	Describe() string
+}
+
+func (l Label) Describe() string {
+	return "label " + string(l)
+}
+
+type Annotation struct {
+	Describer Describer
+	Value     any
+}
+
+func TestParseInterfaceFields(t *testing.T) {
+	input := map[string]any{
+		"Describer": Label("new"),
+		"Value":     map[string]any{"nested": true},
+	}
+
+	actual := new(Annotation)
+	if err := Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Describer == nil || actual.Describer.Describe() != "label new" {
+		t.Errorf("Describer not as expected, got: %v", actual.Describer)
+	}
+
+	if !reflect.DeepEqual(actual.Value, map[string]any{"nested": true}) {
+		t.Errorf("Value not as expected, got: %v", actual.Value)
+	}
+
+	input["Describer"] = "new"
+	err := Parse(input, new(Annotation))
+	if !errors.Is(err, ErrTypeMismatch) || !strings.Contains(err.Error(), "string does not implement kaeru.Describer") {
+		t.Errorf("expected type mismatch error, got: %v", err)
+	}
+}
+
+func TestParseFieldErrorValues(t *testing.T) {
+	input := map[string]any{
+		"Username":  "johndoe",
+		"Email":     "not-an-email",
+		"CreatedAt": "2023-09-11T10:00:00Z",
+		"IsAdmin":   false,
+	}
+
+	err := Parse(input, new(User))
+	expected := `error parsing field Email: value "not-an-email": Email must contain an @ symbol`
+	if err == nil || err.Error() != expected {
+		t.Errorf("error not as expected.\nGot: %v\nWant: %s", err, expected)
+	}
+
+	input["Email"] = strings.Repeat("x", 100)
+	err = Parse(input, new(User))
+	expected = `error parsing field Email: value "` + strings.Repeat("x", 64) + `...": Email must contain an @ symbol`
+	if err == nil || err.Error() != expected {
+		t.Errorf("error not as expected.\nGot: %v\nWant: %s", err, expected)
+	}
+
+	input["Email"] = "not-an-email"
+	comment := map[string]any{
+		"Body":      "A comment that is long enough",
+		"Metadata":  map[string]any{},
+		"Upvotes":   1.0,
+		"Commenter": input,
+	}
+
+	err = Parse(comment, new(Comment))
+	expected = `error parsing field Commenter: error parsing field Email: value "not-an-email": Email must contain an @ symbol`
+	if err == nil || err.Error() != expected {
+		t.Errorf("error not as expected.\nGot: %v\nWant: %s", err, expected)
+	}
+}
+
+type Properties map[string]any
+
+func (p *Properties) ParseMap(m map[string]any) error {
+	if _, ok := m["id"]; !ok {
+		return errors.New("Properties must contain an id")
+	}
+
+	*p = Properties(m)
+
+	return nil
+}
+
+type RawMap map[string]interface{}
+
+func TestParseMapFlavors(t *testing.T) {
+	inputs := []any{
+		map[string]any{"id": 1.0},
+		map[string]interface{}{"id": 1.0},
+		RawMap{"id": 1.0},
+		map[Label]any{"id": 1.0},
+		map[string]float64{"id": 1.0},
+	}
+
+	for _, input := range inputs {
+		actual := new(Properties)
+		if err := Parse(input, actual); err != nil {
+			t.Fatalf("Parse of %T returned an error: %v", input, err)
+		}
+
+		if !reflect.DeepEqual(*actual, Properties{"id": 1.0}) {
+			t.Errorf("Parse of %T not as expected.\nGot: %v", input, *actual)
+		}
+	}
+
+	if err := Parse(RawMap{"name": "x"}, new(Properties)); err == nil {
+		t.Errorf("expected ParseMap error for missing id")
+	}
+}
+
+func TestMustParse(t *testing.T) {
+	labels := new([]Label)
+	MustParse([]any{"new"}, labels)
+
+	if !reflect.DeepEqual(*labels, []Label{"new"}) {
+		t.Errorf("MustParse result not as expected.\nGot: %v", *labels)
+	}
+
+	defer func() {
+		r := recover()
+		err, ok := r.(error)
+		if !ok || !errors.Is(err, ErrRequiredMissing) {
+			t.Errorf("expected panic wrapping ErrRequiredMissing, got: %v", r)
+		}
+	}()
+
+	MustParse(map[string]any{}, new(User))
+}
+
+func TestParseNilPointerElements(t *testing.T) {
+	user := map[string]any{
+		"Username":  "johndoe",
+		"Email":     "john@example.com",
+		"CreatedAt": "2023-09-11T10:00:00Z",
+		"IsAdmin":   true,
+	}
+
+	users := new([]*User)
+	if err := Parse([]any{nil, user, nil}, users); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if len(*users) != 3 || (*users)[0] != nil || (*users)[2] != nil {
+		t.Fatalf("expected nil elements to stay nil, got: %v", *users)
+	}
+
+	if (*users)[1] == nil || (*users)[1].Username != "johndoe" {
+		t.Errorf("expected second element to be parsed, got: %v", (*users)[1])
+	}
+
+	// Pointers to types with a default still get allocated
+	weights := new([]*Weight)
+	if err := Parse([]any{nil, 2.0}, weights); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if len(*weights) != 2 || (*weights)[0] == nil || *(*weights)[0] != 1 || *(*weights)[1] != 2 {
+		t.Errorf("Parse result not as expected, got: %v", *weights)
+	}
+}
+
+func TestParseOnUnknownKey(t *testing.T) {
+	unknown := map[string]any{}
+	p := NewParser(WithOnUnknownKey(func(path string, value any) {
+		unknown[path] = value
+	}))
+
+	input := map[string]any{
+		"Title":   "My First Post",
+		"Body":    "This is the content of my first post.",
+		"Labels":  []any{},
+		"Upvotes": 1.0,
+		"Poster": map[string]any{
+			"Username":  "johndoe",
+			"Email":     "john@example.com",
+			"CreatedAt": "2023-09-11T10:00:00Z",
+			"IsAdmin":   true,
+		},
+		"Comments": []any{
+			map[string]any{
+				"Body":     "A comment that is long enough",
+				"Metadata": map[string]any{},
+				"Upvotes":  1.0,
+				"Commenter": map[string]any{
+					"Username":  "janedoe",
+					"Email":     "jane@example.com",
+					"CreatedAt": "2023-09-10T09:00:00Z",
+					"IsAdmin":   false,
+					"Nickname":  "jane",
+				},
+			},
+		},
+		"FewBytes": []byte{1, 2, 3, 4},
+	}
+
+	if err := p.Parse(input, new(Post)); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := map[string]any{
+		"FewBytes":                       []byte{1, 2, 3, 4},
+		"Comments[0].Commenter.Nickname": "jane",
+	}
+
+	if !reflect.DeepEqual(unknown, expected) {
+		t.Errorf("unexpected unknown keys.\nGot: %v\nWant: %v", unknown, expected)
+	}
+
+	clear(unknown)
+	if err := p.Parse(map[string]any{"name": "x", "email": "x@example.com", "Extra": 1}, new(Plugin)); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if len(unknown) != 0 {
+		t.Errorf("expected keys collected by a remain field not to be reported, got: %v", unknown)
+	}
+}
+
+func TestParseStructArray(t *testing.T) {
+	user := func(name string) map[string]any {
+		return map[string]any{
+			"Username":  name,
+			"Email":     name + "@example.com",
+			"CreatedAt": "2023-09-11T10:00:00Z",
+			"IsAdmin":   false,
+		}
+	}
+
+	createdAt, _ := time.Parse(time.RFC3339, "2023-09-11T10:00:00Z")
+	expectedUser := func(name string) User {
+		return User{Username(name), Email(name + "@example.com"), CreatedAt{createdAt}, false}
+	}
+
+	var users [3]User
+	if err := Parse([]any{user("alice"), user("bob"), user("carol")}, &users); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := [3]User{expectedUser("alice"), expectedUser("bob"), expectedUser("carol")}
+	if users != expected {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", users, expected)
+	}
+
+	if err := Parse([]any{user("dave")}, &users); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected = [3]User{expectedUser("dave")}
+	if users != expected {
+		t.Errorf("expected short input to zero the remaining elements.\nGot: %+v\nWant: %+v", users, expected)
+	}
+
+	err := Parse([]any{user("alice"), user("bob"), user("carol"), user("dave")}, &users)
+	if !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected overflow error for long input, got: %v", err)
+	}
+
+	err = Parse([]any{user("alice"), map[string]any{"Username": "bob"}}, &users)
+	if err == nil || !strings.Contains(err.Error(), "index 1") {
+		t.Errorf("expected element error at index 1, got: %v", err)
+	}
+}
+
+func TestParseFloat32Overflow(t *testing.T) {
+	var f float32
+	if err := Parse(1e40, &f); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected overflow error for 1e40 into float32, got: %v (value %v)", err, f)
+	}
+
+	if err := Parse(-1e40, &f); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected overflow error for -1e40 into float32, got: %v", err)
+	}
+
+	if err := NewParser(WithUseNumber(true)).ParseJsonBytes([]byte("1e40"), &f); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected overflow error for json number 1e40 into float32, got: %v", err)
+	}
+
+	if err := Parse(math.Inf(1), &f); err != nil || !math.IsInf(float64(f), 1) {
+		t.Errorf("expected infinite input to stay infinite, got: %v, %v", f, err)
+	}
+
+	if err := Parse(math.MaxFloat32, &f); err != nil || f != math.MaxFloat32 {
+		t.Errorf("expected MaxFloat32 to fit, got: %v, %v", f, err)
+	}
+}
+
+type Interval int64
+
+func (d *Interval) ParseAny(v any) error {
+	s, ok := v.(string)
+	if !ok {
+		return ErrSkip
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Interval(parsed)
+
+	return nil
+}
+
+func TestParseAnySkip(t *testing.T) {
+	var d Interval
+	if err := Parse("2s", &d); err != nil || d != Interval(2*time.Second) {
+		t.Errorf("expected ParseAny to handle strings, got: %d, %v", d, err)
+	}
+
+	if err := Parse(1000.0, &d); err != nil || d != 1000 {
+		t.Errorf("expected ErrSkip to fall back to default parsing, got: %d, %v", d, err)
+	}
+
+	if err := Parse("soon", &d); err == nil || errors.Is(err, ErrSkip) {
+		t.Errorf("expected ParseAny error to be returned, got: %v", err)
+	}
+
+	if err := Parse(true, &d); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected default parsing error after ErrSkip, got: %v", err)
+	}
+}
+
+type Backoff struct {
+	Initial time.Duration
+	Max     *time.Duration
+}
+
+func TestParseDuration(t *testing.T) {
+	actual := new(Backoff)
+	if err := Parse(map[string]any{"Initial": "1h30m", "Max": 2e9}, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Initial != 90*time.Minute || actual.Max == nil || *actual.Max != 2*time.Second {
+		t.Errorf("Parse result not as expected, got: %v %v", actual.Initial, actual.Max)
+	}
+
+	if err := ParseJsonBytes([]byte(`{"Initial": "250ms"}`), actual); err != nil || actual.Initial != 250*time.Millisecond {
+		t.Errorf("expected 250ms from json, got: %v, %v", actual.Initial, err)
+	}
+
+	if err := Parse(map[string]any{"Initial": "soon"}, new(Backoff)); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected type mismatch for invalid duration, got: %v", err)
+	}
+}
+
+func TestParseMapOfStructs(t *testing.T) {
+	input := map[string]any{
+		"john": map[string]any{
+			"Username":  "johndoe",
+			"Email":     "john@example.com",
+			"CreatedAt": "2023-09-11T10:00:00Z",
+			"IsAdmin":   true,
+		},
+		"jane": map[string]any{
+			"Username":  "janedoe",
+			"Email":     "jane@example.com",
+			"CreatedAt": "2023-09-10T09:00:00Z",
+			"IsAdmin":   false,
+		},
+	}
+
+	johnTime, _ := time.Parse(time.RFC3339, "2023-09-11T10:00:00Z")
+	janeTime, _ := time.Parse(time.RFC3339, "2023-09-10T09:00:00Z")
+	expected := map[string]User{
+		"john": {"johndoe", "john@example.com", CreatedAt{johnTime}, true},
+		"jane": {"janedoe", "jane@example.com", CreatedAt{janeTime}, false},
+	}
+
+	actual := map[string]User{}
+	if err := Parse(input, &actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	input["jane"].(map[string]any)["Email"] = "invalid"
+	err := Parse(input, &actual)
+	if err == nil || !strings.Contains(err.Error(), "Email") {
+		t.Errorf("expected nested Email error, got: %v", err)
+	}
+
+	if err := Parse(map[string]any{"john": "johndoe"}, &actual); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected type mismatch for a non map value, got: %v", err)
+	}
+}
+
+func TestParseSliceCapacity(t *testing.T) {
+	input := make([]any, 2, 1000)
+	input[0], input[1] = "new", "featured"
+
+	var labels []Label
+	if err := Parse(input, &labels); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if len(labels) != 2 || cap(labels) != len(labels) {
+		t.Errorf("expected cap to equal len, got len %d cap %d", len(labels), cap(labels))
+	}
+}
+
+type PluginConfig struct {
+	Name   string
+	Config json.RawMessage
+}
+
+func TestParseRawMessage(t *testing.T) {
+	data := []byte(`{"Name": "cache", "Config": {"size": 12345678901234567890, "keys": ["a", "b"]}}`)
+
+	actual := new(PluginConfig)
+	if err := NewParser(WithUseNumber(true)).ParseJsonBytes(data, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if string(actual.Config) != `{"keys":["a","b"],"size":12345678901234567890}` {
+		t.Errorf("unexpected raw config: %s", actual.Config)
+	}
+
+	actual = new(PluginConfig)
+	if err := Parse(map[string]any{"Name": "cache", "Config": []any{1, "two"}}, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if string(actual.Config) != `[1,"two"]` {
+		t.Errorf("unexpected raw config: %s", actual.Config)
+	}
+
+	if err := Parse(map[string]any{"Name": "cache", "Config": func() {}}, new(PluginConfig)); err == nil {
+		t.Errorf("expected error for an input that cannot be encoded as JSON")
+	}
+}
+
+func TestParseTrimStrings(t *testing.T) {
+	trim := NewParser(WithTrimStrings(true))
+
+	actual := new(User)
+	err := trim.Parse(map[string]any{
+		"Username":  "  JohnDoe ",
+		"Email":     "\tjohn@example.com\n",
+		"CreatedAt": " 2023-09-11T10:00:00Z ",
+		"IsAdmin":   false,
+	}, actual)
+	if err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Username != "JohnDoe" || actual.Email != "john@example.com" {
+		t.Errorf("expected ParseString to see trimmed values, got: %+v", *actual)
+	}
+
+	if err := Parse(" JohnDoe ", new(Username)); err == nil {
+		t.Errorf("expected untrimmed input to fail without TrimStrings")
+	}
+
+	lower := NewParser(WithTrimStrings(true), WithStringTransform(strings.ToLower))
+
+	var names map[string]string
+	if err := lower.Parse(map[string]any{" Alice ": "BOB"}, &names); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(names, map[string]string{"alice": "bob"}) {
+		t.Errorf("expected trimmed and lowercased strings, got: %q", names)
+	}
+}
+
+func TestParseTrimStringsBulk(t *testing.T) {
+	trim := NewParser(WithTrimStrings(true))
+
+	input := []any{" new ", "featured\n"}
+	var tags Tags
+	if err := trim.Parse(input, &tags); err != nil || !reflect.DeepEqual(tags, Tags{"new", "featured"}) {
+		t.Errorf("expected ParseStringSlice to see trimmed values, got: %q, %v", tags, err)
+	}
+
+	if input[0] != " new " {
+		t.Errorf("expected the input to be left unchanged, got: %q", input)
+	}
+
+	var headers Headers
+	if err := trim.Parse(map[string]any{"Accept": " text/html "}, &headers); err != nil || headers["Accept"] != "text/html" {
+		t.Errorf("expected ParseStringMap to see trimmed values, got: %q, %v", headers, err)
+	}
+
+	var properties Properties
+	err := trim.Parse(map[string]any{"id": " 1 ", "tags": []any{" a "}, "size": 2.0}, &properties)
+	if err != nil || !reflect.DeepEqual(properties, Properties{"id": "1", "tags": []any{"a"}, "size": 2.0}) {
+		t.Errorf("expected ParseMap to see trimmed values, got: %v, %v", properties, err)
+	}
+
+	var samples Samples
+	if err := trim.Parse([]any{" a ", 1.0}, &samples); err != nil || !reflect.DeepEqual(samples, Samples{"a", 1.0}) {
+		t.Errorf("expected ParseSlice to see trimmed values, got: %q, %v", samples, err)
+	}
+}
+
+func TestParseExpandEnv(t *testing.T) {
+	env := map[string]string{"HOME": "/home/joe", "USER": "joe", "PAD": "  "}
+	lookup := func(name string) string { return env[name] }
+
+	type Paths struct {
+		Data     string
+		Owner    Username
+		Missing  string
+		Literal  string
+		Trimmed  string
+		Mentions []string
+	}
+
+	input := map[string]any{
+		"Data":     "${HOME}/data",
+		"Owner":    "$USER",
+		"Missing":  "${UNSET}/cache",
+		"Literal":  "no variables",
+		"Trimmed":  "${PAD}value${PAD}",
+		"Mentions": []any{"$USER", "${USER}_admin"},
+	}
+
+	p := NewParser(WithExpandEnv(true), WithLookupEnv(lookup), WithTrimStrings(true))
+
+	var actual Paths
+	if err := p.Parse(input, &actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := Paths{
+		Data:     "/home/joe/data",
+		Owner:    "joe",
+		Missing:  "/cache",
+		Literal:  "no variables",
+		Trimmed:  "value",
+		Mentions: []string{"joe", "joe_admin"},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	var data string
+	if err := Parse("${HOME}/data", &data); err != nil || data != "${HOME}/data" {
+		t.Errorf("expected variables to be kept without ExpandEnv, got: %q, %v", data, err)
+	}
+
+	t.Setenv("KAERU_TEST_DIR", "/srv")
+	if err := NewParser(WithExpandEnv(true)).Parse("$KAERU_TEST_DIR/app", &data); err != nil || data != "/srv/app" {
+		t.Errorf("expected the real environment by default, got: %q, %v", data, err)
+	}
+
+	var tags Tags
+	if err := p.Parse([]any{"$USER", "team"}, &tags); err != nil || !reflect.DeepEqual(tags, Tags{"joe", "team"}) {
+		t.Errorf("expected ParseStringSlice to see expanded values, got: %q, %v", tags, err)
+	}
+
+	var headers Headers
+	if err := p.Parse(map[string]any{"Home": "$HOME"}, &headers); err != nil || headers["Home"] != "/home/joe" {
+		t.Errorf("expected ParseStringMap to see expanded values, got: %q, %v", headers, err)
+	}
+}
+
+func TestParseSliceElementError(t *testing.T) {
+	comment := func(body string) map[string]any {
+		return map[string]any{
+			"Body":     body,
+			"Metadata": map[string]any{},
+			"Upvotes":  1.0,
+			"Commenter": map[string]any{
+				"Username":  "janedoe",
+				"Email":     "jane@example.com",
+				"CreatedAt": "2023-09-10T09:00:00Z",
+				"IsAdmin":   false,
+			},
+		}
+	}
+
+	input := []any{comment("A comment that is long enough"), comment("short")}
+
+	err := Parse(input, new([]Comment))
+	expected := `error parsing element at index 1: error parsing field Body: value "short": Body must be between 10 and 5000 characters long`
+	if err == nil || err.Error() != expected {
+		t.Errorf("unexpected error.\nGot: %v\nWant: %s", err, expected)
+	}
+
+	existing := []Comment{{}}
+	err = NewParser(WithMerge(true)).Parse(input, &existing)
+	if err == nil || !strings.HasPrefix(err.Error(), "error parsing element at index 1:") {
+		t.Errorf("expected merged element error at index 1, got: %v", err)
+	}
+}
+
+func TestParseCollectErrors(t *testing.T) {
+	p := NewParser(WithCollectErrors(true))
+
+	input := map[string]any{
+		"Title": "My First Post",
+		"Poster": map[string]any{
+			"Username": "johndoe",
+			"Email":    "invalid",
+		},
+		"Labels": []any{"new", "", "featured"},
+	}
+
+	err := p.Parse(input, new(Post))
+	if err == nil {
+		t.Fatalf("expected errors")
+	}
+
+	expected := []string{
+		"error parsing field Body: required value is missing",
+		"error parsing field Labels: error parsing element at index 1: Label must be between 1 and 20 characters long",
+		"error parsing field Upvotes: required value is missing",
+		"error parsing field Poster: error parsing field Email: value \"invalid\": Email must contain an @ symbol",
+		"error parsing field Poster: error parsing field CreatedAt: error parsing field Time: required value is missing",
+		"error parsing field Poster: error parsing field IsAdmin: required value is missing",
+		"error parsing field Comments: required value is missing",
+	}
+
+	if err.Error() != strings.Join(expected, "\n") {
+		t.Errorf("unexpected errors.\nGot:\n%v\nWant:\n%s", err, strings.Join(expected, "\n"))
+	}
+
+	if !errors.Is(err, ErrRequiredMissing) {
+		t.Errorf("expected errors.Is to find ErrRequiredMissing")
+	}
+
+	err = Parse(input, new(Post))
+	if err == nil || strings.Contains(err.Error(), "\n") {
+		t.Errorf("expected a single error without CollectErrors, got: %v", err)
+	}
+}
+
+func TestParseCollectErrorsKeys(t *testing.T) {
+	p := NewParser(WithCollectErrors(true), WithDisallowDuplicateKeys(true))
+
+	var ports map[int]string
+	err := p.Parse(map[string]any{"x": "a", "y": "b"}, &ports)
+	if !errors.Is(err, ErrTypeMismatch) || strings.Count(err.Error(), "\n") != 1 {
+		t.Errorf("expected both map key errors, got: %v", err)
+	}
+
+	var labels struct {
+		Extra map[string]Label `parse:",remain"`
+	}
+	err = p.Parse(map[string]any{"a": "", "b": ""}, &labels)
+	if strings.Count(err.Error(), "error parsing field Extra: ") != 2 {
+		t.Errorf("expected both remain errors prefixed with the field, got: %v", err)
+	}
+
+	var contact struct {
+		Contact Contact
+		Title   Title
+	}
+	err = p.Parse(map[string]any{"Contact": map[string]any{"email": "a@example.com", "mail": "b@example.com"}}, &contact)
+	if !errors.Is(err, ErrDuplicateKey) || !errors.Is(err, ErrRequiredMissing) {
+		t.Errorf("expected the duplicate key and the missing title, got: %v", err)
+	}
+
+	strict := NewParser(WithCollectErrors(true), WithDisallowUnknownKeys(true))
+	err = strict.Parse(map[string]any{"Title": "", "extra": 1.0}, &contact)
+	if !errors.Is(err, ErrUnknownKey) || !errors.Is(err, ErrRequiredMissing) {
+		t.Errorf("expected the unknown key alongside the field errors, got: %v", err)
+	}
+
+	var rest struct {
+		Title Title
+		Extra map[string]Label `parse:",remain"`
+	}
+	err = p.Parse(map[string]any{"a": ""}, &rest)
+	if !errors.Is(err, ErrRequiredMissing) || !strings.Contains(err.Error(), "error parsing field Extra") {
+		t.Errorf("expected the remain error alongside the field errors, got: %v", err)
+	}
+}
+
+func TestParseNegativeIntoUnsigned(t *testing.T) {
+	outputs := []any{new(uint), new(uint8), new(uint16), new(uint32), new(uint64)}
+	inputs := []any{-5, int64(-5), -5.0, float32(-0.5)}
+
+	for _, output := range outputs {
+		for _, input := range inputs {
+			err := Parse(input, output)
+			if !errors.Is(err, ErrOverflow) || !strings.Contains(err.Error(), "negative value") {
+				t.Errorf("expected negative value error for %v (%T) into %T, got: %v", input, input, output, err)
+			}
+		}
+
+		if err := Parse(5, output); err != nil {
+			t.Errorf("Parse of 5 into %T returned an error: %v", output, err)
+		}
+	}
+
+	err := Parse(-5, new(uint))
+	if err == nil || !strings.Contains(err.Error(), "negative value -5 cannot be parsed into uint") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestParseMapOfPointers(t *testing.T) {
+	input := map[string]any{
+		"john": map[string]any{
+			"Username":  "johndoe",
+			"Email":     "john@example.com",
+			"CreatedAt": "2023-09-11T10:00:00Z",
+			"IsAdmin":   true,
+		},
+		"deleted": nil,
+	}
+
+	actual := map[string]*User{}
+	if err := Parse(input, &actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if len(actual) != 2 {
+		t.Fatalf("expected 2 entries, got: %v", actual)
+	}
+
+	if deleted, ok := actual["deleted"]; !ok || deleted != nil {
+		t.Errorf("expected a nil entry for a null value, got: %v, %v", deleted, ok)
+	}
+
+	if john := actual["john"]; john == nil || john.Username != "johndoe" || !john.IsAdmin {
+		t.Errorf("expected john to be allocated and parsed, got: %+v", john)
+	}
+
+	if err := ParseJsonBytes([]byte(`{"a": null, "b": {"Username": "x"}}`), &actual); err == nil {
+		t.Errorf("expected missing fields of b to be reported")
+	}
+}
+
+// Samples implements only ParseSlice so typed slice inputs reach it
+type Samples []any
+
+func (s *Samples) ParseSlice(v []any) error {
+	*s = append(Samples{}, v...)
+	return nil
+}
+
+func TestParseSliceTypedInput(t *testing.T) {
+	for _, input := range []any{[]int{1, 2, 3}, []string{"a", "b"}, []any{1, "b", nil}} {
+		var actual Samples
+		if err := Parse(input, &actual); err != nil {
+			t.Fatalf("Parse returned an error for %T: %v", input, err)
+		}
+
+		if len(actual) != reflect.ValueOf(input).Len() {
+			t.Errorf("expected every element of %T, got: %v", input, actual)
+		}
+	}
+}
+
+// Draft is a Go value input, with the pointer and array fields that used to
+// panic when a struct was parsed into another type
+type Draft struct {
+	Title    *string
+	Body     **string
+	Labels   [2]string
+	Upvotes  *int
+	Poster   *map[string]any
+	Comments []Comment
+}
+
+func TestParseGoValueInputs(t *testing.T) {
+	title := "My First Post"
+	body := "This is the content of my first post."
+	bodyPtr := &body
+	upvotes := 42
+
+	draft := Draft{
+		Title:   &title,
+		Body:    &bodyPtr,
+		Labels:  [2]string{"new", "featured"},
+		Upvotes: &upvotes,
+		Poster: &map[string]any{
+			"Username":  "johndoe",
+			"Email":     "john@example.com",
+			"CreatedAt": "2023-09-11T10:00:00Z",
+			"IsAdmin":   true,
+		},
+		Comments: []Comment{},
+	}
+
+	actual := new(Post)
+	if err := Parse(&draft, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Title != "My First Post" || actual.Upvotes != 42 || actual.Poster.Username != "johndoe" ||
+		!reflect.DeepEqual(actual.Labels, []Label{"new", "featured"}) {
+		t.Errorf("Parse result not as expected.\nGot: %+v", actual)
+	}
+
+	var labels [2]Label
+	if err := Parse([3]string{"a", "b", "c"}, &labels); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected ErrOverflow for a longer array, got: %v", err)
+	}
+
+	var note *string
+	if err := Parse((*string)(nil), &note); err != nil || note != nil {
+		t.Errorf("expected a nil pointer input to leave the output nil, got: %v, %v", note, err)
+	}
+
+	for _, input := range []any{make(chan int), func() {}, map[string]any{"Title": make(chan int)}} {
+		if err := Parse(input, new(Post)); !errors.Is(err, ErrUnsupportedKind) {
+			t.Errorf("expected ErrUnsupportedKind for %T, got: %v", input, err)
+		}
+	}
+}
+
+func TestParseNilAndEmptySlices(t *testing.T) {
+	type Listing struct {
+		Labels []Label
+		Scores []int
+		Grid   [][]int
+	}
+
+	actual := new(Listing)
+	err := ParseJsonBytes([]byte(`{"Labels": null, "Scores": [], "Grid": [[1], null, []]}`), actual)
+	if err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Labels != nil {
+		t.Errorf("expected null to leave a nil slice, got: %#v", actual.Labels)
+	}
+
+	if actual.Scores == nil || len(actual.Scores) != 0 {
+		t.Errorf("expected [] to give an empty non-nil slice, got: %#v", actual.Scores)
+	}
+
+	if len(actual.Grid) != 3 || actual.Grid[1] != nil || actual.Grid[2] == nil {
+		t.Errorf("expected nested null and [] to stay distinct, got: %#v", actual.Grid)
+	}
+
+	actual = new(Listing)
+	if err := Parse(map[string]any{"Labels": []string(nil), "Scores": []any{}, "Grid": [][]int{}}, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Labels != nil || actual.Scores == nil || actual.Grid == nil {
+		t.Errorf("expected nil and empty Go slices to be kept apart, got: %#v", actual)
+	}
+
+	err = ParseJsonBytes([]byte(`{"Scores": [], "Grid": []}`), new(Listing))
+	if !errors.Is(err, ErrRequiredMissing) {
+		t.Errorf("expected an absent slice to still be required, got: %v", err)
+	}
+}
+
+// StrictUser rejects unknown keys wherever it appears while the structs
+// around it keep ignoring them
+type StrictUser struct {
+	Username Username
+	Email    Email
+}
+
+func (u *StrictUser) ParseOptions() []Option {
+	return []Option{WithDisallowUnknownKeys(true), WithTrimStrings(true)}
+}
+
+type Team struct {
+	Name    string
+	Owner   StrictUser
+	Members []StrictUser
+}
+
+func TestParseStructOptions(t *testing.T) {
+	input := map[string]any{
+		"Name":    " core ",
+		"Owner":   map[string]any{"Username": " johndoe ", "Email": "john@example.com"},
+		"Members": []any{},
+		"Founded": 2020,
+	}
+
+	actual := new(Team)
+	if err := Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Name != " core " {
+		t.Errorf("expected the options of Owner not to reach its parent, got: %q", actual.Name)
+	}
+
+	if actual.Owner.Username != "johndoe" {
+		t.Errorf("expected the options of Owner to apply to its fields, got: %q", actual.Owner.Username)
+	}
+
+	input["Members"] = []any{map[string]any{"Username": "janedoe", "Email": "jane@example.com", "Role": "admin", "Age": 30}}
+
+	err := Parse(input, new(Team))
+	if !errors.Is(err, ErrUnknownKey) || !strings.Contains(err.Error(), "unknown key: Age, Role") {
+		t.Errorf("expected unknown keys of a member to be rejected, got: %v", err)
+	}
+}
+
+// Greeting is a func output filled by its own parser
+type Greeting func() string
+
+func (g *Greeting) ParseAny(v any) error {
+	name, ok := v.(string)
+	if !ok {
+		return errors.New("greeting must be a name")
+	}
+
+	*g = func() string { return "hello " + name }
+	return nil
+}
+
+func TestParseUnsupportedOutputs(t *testing.T) {
+	type Worker struct {
+		Name    string
+		Done    chan struct{}
+		OnClose func()
+	}
+
+	for _, input := range []map[string]any{
+		{"Name": "a"},
+		{"Name": "a", "Done": []any{}, "OnClose": "close"},
+	} {
+		err := Parse(input, new(Worker))
+		if !errors.Is(err, ErrUnsupportedKind) || !strings.Contains(err.Error(), "field Done") {
+			t.Errorf("expected an unsupported kind error for Done, got: %v", err)
+		}
+	}
+
+	type Skipped struct {
+		Name  string
+		Done  chan struct{} `parse:"-"`
+		Greet Greeting
+	}
+
+	actual := new(Skipped)
+	if err := Parse(map[string]any{"Name": "a", "Greet": "joe"}, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Greet() != "hello joe" {
+		t.Errorf("expected Greeting to be parsed by ParseAny, got: %q", actual.Greet())
+	}
+}
+
+func TestParseAbsentPointers(t *testing.T) {
+	input := map[string]any{
+		"Title":   "My First Post",
+		"Body":    "This is the content of my first post.",
+		"Labels":  []any{"new"},
+		"Upvotes": 42.0,
+		"Poster": map[string]any{
+			"Username":  "johndoe",
+			"Email":     "john@example.com",
+			"CreatedAt": "2023-09-11T10:00:00Z",
+			"IsAdmin":   true,
+		},
+		"Comments": []any{},
+	}
+
+	actual := new(Post)
+	if err := Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Metadata != nil || actual.AdminNote != nil {
+		t.Errorf("expected absent pointer fields to stay nil, got: %v, %v", actual.Metadata, actual.AdminNote)
+	}
+
+	input["Metadata"] = nil
+	input["AdminNote"] = nil
+
+	actual = new(Post)
+	if err := Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Metadata != nil || actual.AdminNote != nil {
+		t.Errorf("expected null pointer fields to stay nil, got: %v, %v", actual.Metadata, actual.AdminNote)
+	}
+
+	input["Metadata"] = map[string]any{}
+	input["AdminNote"] = ""
+
+	actual = new(Post)
+	if err := Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Metadata == nil || len(*actual.Metadata) != 0 || actual.AdminNote == nil || *actual.AdminNote != "" {
+		t.Errorf("expected present empty values to be allocated, got: %v, %v", actual.Metadata, actual.AdminNote)
+	}
+}
+
+func TestParseNumericSlices(t *testing.T) {
+	var floats []float64
+	if err := Parse([]any{1.5, 2, int64(3), uint8(4)}, &floats); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(floats, []float64{1.5, 2, 3, 4}) {
+		t.Errorf("expected mixed numbers as floats, got: %v", floats)
+	}
+
+	var ints []int32
+	if err := Parse([]any{1.0, 2.0, 3.0}, &ints); err != nil || !reflect.DeepEqual(ints, []int32{1, 2, 3}) {
+		t.Errorf("expected [1 2 3], got: %v, %v", ints, err)
+	}
+
+	var small []float32
+	err := Parse([]any{1.0, math.MaxFloat64}, &small)
+	if !errors.Is(err, ErrOverflow) || !strings.Contains(err.Error(), "index 1") {
+		t.Errorf("expected overflow of element 1, got: %v", err)
+	}
+
+	var counts []uint
+	err = NewParser(WithCollectErrors(true)).Parse([]any{-1.0, 2.0, -3.0}, &counts)
+	if !errors.Is(err, ErrOverflow) || !strings.Contains(err.Error(), "index 0") || !strings.Contains(err.Error(), "index 2") {
+		t.Errorf("expected both negative elements to be reported, got: %v", err)
+	}
+
+	err = NewParser(WithDisallowTypeNarrowing(true)).Parse([]any{1.0, 2.5}, &ints)
+	if !errors.Is(err, ErrNarrowing) {
+		t.Errorf("expected narrowing error, got: %v", err)
+	}
+
+	if err := NewParser(WithWeaklyTypedInput(true)).Parse([]any{1.0, "2"}, &floats); err != nil || floats[1] != 2 {
+		t.Errorf("expected a numeric string element to use the regular path, got: %v, %v", floats, err)
+	}
+}
+
+type Circle struct{ Radius float64 }
+type Square struct{ Side float64 }
+
+// Shape is a union picked by the kind field of the input
+type Shape struct {
+	Circle *Circle
+	Square *Square
+	viaAny bool
+}
+
+func (sh *Shape) ParseReflect(v reflect.Value) error {
+	if v.Kind() != reflect.Map {
+		return ErrSkip
+	}
+
+	kind := v.MapIndex(reflect.ValueOf("kind"))
+	if !kind.IsValid() {
+		return errors.New("shape has no kind")
+	}
+
+	switch kind.Interface() {
+	case "circle":
+		sh.Circle = new(Circle)
+		return Parse(map[string]any{"Radius": v.MapIndex(reflect.ValueOf("radius")).Interface()}, sh.Circle)
+	case "square":
+		sh.Square = new(Square)
+		return Parse(map[string]any{"Side": v.MapIndex(reflect.ValueOf("side")).Interface()}, sh.Square)
+	default:
+		return fmt.Errorf("unknown shape %v", kind)
+	}
+}
+
+func (sh *Shape) ParseAny(v any) error {
+	sh.viaAny = true
+	return ErrSkip
+}
+
+func TestParseReflect(t *testing.T) {
+	var shapes []Shape
+	input := []any{
+		map[string]any{"kind": "circle", "radius": 2.0},
+		map[string]any{"kind": "square", "side": 3.0},
+	}
+
+	if err := Parse(input, &shapes); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := []Shape{{Circle: &Circle{2}}, {Square: &Square{3}}}
+	if !reflect.DeepEqual(shapes, expected) {
+		t.Errorf("expected ParseReflect to run ahead of ParseAny, got: %+v", shapes)
+	}
+
+	if err := Parse(map[string]any{"kind": "triangle"}, new(Shape)); err == nil || !strings.Contains(err.Error(), "unknown shape") {
+		t.Errorf("expected an unknown shape error, got: %v", err)
+	}
+
+	shape := new(Shape)
+	if err := Parse(map[any]any{"Circle": map[string]any{"Radius": 1.0}}, shape); err == nil {
+		t.Errorf("expected a map without a string kind key to be rejected")
+	}
+
+	shape = new(Shape)
+	if err := Parse("circle", shape); !errors.Is(err, ErrTypeMismatch) || !shape.viaAny {
+		t.Errorf("expected ErrSkip to fall through to ParseAny and the default parsing, got: %v, %+v", err, shape)
+	}
+}
+
+func TestParseOptionalScalars(t *testing.T) {
+	type Patch struct {
+		Admin *bool
+		Limit *int
+	}
+
+	ptr := func(v any) string {
+		switch v := v.(type) {
+		case *bool:
+			if v != nil {
+				return fmt.Sprint(*v)
+			}
+		case *int:
+			if v != nil {
+				return fmt.Sprint(*v)
+			}
+		}
+
+		return "nil"
+	}
+
+	cases := []struct {
+		input string
+		admin string
+		limit string
+	}{
+		{`{}`, "nil", "nil"},
+		{`{"Admin": null, "Limit": null}`, "nil", "nil"},
+		{`{"Admin": true, "Limit": 10}`, "true", "10"},
+		{`{"Admin": false, "Limit": 0}`, "false", "0"},
+	}
+
+	for _, c := range cases {
+		actual := new(Patch)
+		if err := ParseJsonBytes([]byte(c.input), actual); err != nil {
+			t.Fatalf("Parse returned an error for %s: %v", c.input, err)
+		}
+
+		if ptr(actual.Admin) != c.admin || ptr(actual.Limit) != c.limit {
+			t.Errorf("expected %s and %s for %s, got: %s and %s", c.admin, c.limit, c.input, ptr(actual.Admin), ptr(actual.Limit))
+		}
+	}
+
+	merge := NewParser(WithMerge(true))
+	existing := true
+	patch := &Patch{Admin: &existing}
+
+	if err := merge.ParseJsonBytes([]byte(`{"Limit": 5}`), patch); err != nil || ptr(patch.Admin) != "true" || ptr(patch.Limit) != "5" {
+		t.Errorf("expected an absent key to keep the existing value when merging, got: %s, %s, %v", ptr(patch.Admin), ptr(patch.Limit), err)
+	}
+
+	if err := merge.ParseJsonBytes([]byte(`{"Admin": false}`), patch); err != nil || ptr(patch.Admin) != "false" {
+		t.Errorf("expected an explicit false to be set when merging, got: %s, %v", ptr(patch.Admin), err)
+	}
+}
+
+func FuzzParse(f *testing.F) {
+	f.Add([]byte(`{"Title": "My First Post", "Body": "This is the content of my first post.", "Labels": ["new"], "Upvotes": 42, "Poster": {"Username": "johndoe", "Email": "john@example.com", "CreatedAt": "2023-09-11T10:00:00Z", "IsAdmin": true}, "Comments": []}`))
+	f.Add([]byte(`{"Metadata": {"a": "b"}, "Comments": [{"Commenter": null, "Metadata": null}]}`))
+	f.Add([]byte(`{"Labels": "one", "Upvotes": "42", "Poster": [], "AdminNote": 1}`))
+	f.Add([]byte(`[{"Title": 1}]`))
+	f.Add([]byte(`null`))
+
+	parsers := []*Parser{
+		NewParser(),
+		NewParser(WithUseNumber(true), WithCollectErrors(true)),
+		NewParser(WithWeaklyTypedInput(true), WithMerge(true), WithDeepCopy(true)),
+	}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, parser := range parsers {
+			// Any outcome but a panic is fine, the error is checked by the
+			// other tests
+			_ = parser.ParseJsonBytes(data, new(Post))
+			_ = parser.ParseJsonBytes(data, new([]Comment))
+			_ = parser.ParseJsonBytes(data, new(map[string]any))
+		}
+	})
+}
+
+func BenchmarkParsePost(b *testing.B) {
+	input := map[string]any{
+		"Title":    "My First Post",
+		"Body":     "This is the content of my first post. It's pretty exciting!",
+		"Metadata": map[string]any{"category": "tech", "tags": "golang,testing"},
+		"Labels":   []any{"new", "featured"},
+		"Upvotes":  42.0,
+		"Poster": map[string]any{
+			"Username":  "johndoe",
+			"Email":     "john@example.com",
+			"CreatedAt": "2023-09-11T10:00:00Z",
+			"IsAdmin":   true,
+		},
+		"Comments": []any{},
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if err := Parse(input, new(Post)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseFloatSlice(b *testing.B) {
+	input := make([]any, 1_000_000)
+	for i := range input {
+		input[i] = float64(i) / 2
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var out []float64
+		if err := Parse(input, &out); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestParseJsonMaxInputSize(t *testing.T) {
+	data := `{"Title": "My First Post", "Upvotes": 42}`
+	type Summary struct {
+		Title   Title
+		Upvotes Upvotes
+	}
+
+	exact := NewParser(WithMaxInputSize(int64(len(data))))
+	if err := exact.ParseJson(strings.NewReader(data), new(Summary)); err != nil {
+		t.Errorf("expected input of exactly the limit to parse, got: %v", err)
+	}
+
+	if err := exact.ParseJsonBytes([]byte(data), new(Summary)); err != nil {
+		t.Errorf("expected input of exactly the limit to parse, got: %v", err)
+	}
+
+	small := NewParser(WithMaxInputSize(16))
+	if err := small.ParseJson(strings.NewReader(data), new(Summary)); !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("expected ErrInputTooLarge from ParseJson, got: %v", err)
+	}
+
+	if err := small.ParseJsonBytes([]byte(data), new(Summary)); !errors.Is(err, ErrInputTooLarge) {
+		t.Errorf("expected ErrInputTooLarge from ParseJsonBytes, got: %v", err)
+	}
+
+	strict := NewParser(WithUseNumber(true), WithDisallowUnknownKeys(true))
+	err := strict.ParseJson(strings.NewReader(`{"Title": "My First Post", "Upvotes": 42, "Draft": true}`), new(Summary))
+	if !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey for Draft, got: %v", err)
+	}
+
+	var n any
+	if err := strict.ParseJson(strings.NewReader(`12345678901234567890`), &n); err != nil || n != json.Number("12345678901234567890") {
+		t.Errorf("expected a json.Number, got: %v, %v", n, err)
+	}
+}
+
+type Extension interface {
+	Name() string
+}
+
+type LoggerExtension struct{ Level string }
+
+func (LoggerExtension) Name() string { return "logger" }
+
+type MetricsExtension struct{ Port int }
+
+func (*MetricsExtension) Name() string { return "metrics" }
+
+func TestParseInterfaceSlices(t *testing.T) {
+	metrics := &MetricsExtension{Port: 9090}
+
+	var extensions []Extension
+	if err := Parse([]any{LoggerExtension{"debug"}, metrics}, &extensions); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := []Extension{LoggerExtension{"debug"}, metrics}
+	if !reflect.DeepEqual(extensions, expected) {
+		t.Errorf("expected the extensions to be set as is, got: %+v", extensions)
+	}
+
+	if extensions[1] != Extension(metrics) {
+		t.Errorf("expected a pointer receiver implementation to keep its pointer")
+	}
+
+	var fixed [2]Extension
+	if err := Parse([]*MetricsExtension{metrics, metrics}, &fixed); err != nil || fixed[0] != Extension(metrics) {
+		t.Errorf("expected a typed slice of pointers to fill an array of interfaces, got: %+v, %v", fixed, err)
+	}
+
+	var copied []Extension
+	if err := NewParser(WithDeepCopy(true)).Parse([]any{metrics}, &copied); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if copied[0] == Extension(metrics) || !reflect.DeepEqual(copied[0], Extension(metrics)) {
+		t.Errorf("expected DeepCopy to copy the pointer implementation, got: %+v", copied[0])
+	}
+
+	err := Parse([]any{LoggerExtension{}, MetricsExtension{}}, &extensions)
+	if !errors.Is(err, ErrTypeMismatch) || !strings.Contains(err.Error(), "index 1") {
+		t.Errorf("expected a value missing pointer methods to be rejected, got: %v", err)
+	}
+}
+
+type Nickname string
+type Karma int
+type Flag bool
+
+func TestParseRequireCustomParsers(t *testing.T) {
+	type Account struct {
+		Email   Email
+		Timeout time.Duration
+		Bio     string
+	}
+
+	strict := NewParser(WithRequireCustomParsers(true))
+
+	tests := []struct {
+		input  any
+		output any
+	}{
+		{"joe", new(Nickname)},
+		{3, new(Karma)},
+		{true, new(Flag)},
+		{[]any{"joe"}, new([]Nickname)},
+	}
+
+	for _, tt := range tests {
+		if err := Parse(tt.input, tt.output); err != nil {
+			t.Errorf("expected %T to be converted by default, got: %v", tt.output, err)
+		}
+
+		if err := strict.Parse(tt.input, tt.output); !errors.Is(err, ErrMissingParser) {
+			t.Errorf("expected %T to require a parser, got: %v", tt.output, err)
+		}
+	}
+
+	var account Account
+	err := strict.Parse(map[string]any{"Email": "joe@example.com", "Timeout": 5.0, "Bio": "hi"}, &account)
+	if err != nil || account.Email != "joe@example.com" || account.Timeout != 5 || account.Bio != "hi" {
+		t.Errorf("expected parsers, builtin types and durations to be accepted, got: %+v, %v", account, err)
+	}
+
+	weak := NewParser(WithRequireCustomParsers(true), WithWeaklyTypedInput(true))
+	if err := weak.Parse("3", new(Karma)); !errors.Is(err, ErrMissingParser) {
+		t.Errorf("expected weak conversions to require a parser too, got: %v", err)
+	}
+}
+
+func TestParseRequiredAndDefaults(t *testing.T) {
+	type Plain struct {
+		Title Title `parse:"title"`
+	}
+
+	type Defaulted struct {
+		Title Title `parse:"title,default=Untitled"`
+	}
+
+	type Required struct {
+		Title *Title `parse:"title,required"`
+	}
+
+	type RequiredDefaulted struct {
+		Title *Title `parse:"title,required,default=Untitled"`
+	}
+
+	type OmitEmpty struct {
+		Title Title `parse:"title,omitempty"`
+	}
+
+	type Optional struct {
+		Title *Title `parse:"title"`
+	}
+
+	invalid := errors.New("Title must be between 3 and 100 characters long")
+	title := func(t Title) *Title { return &t }
+
+	absent := map[string]any{}
+	null := map[string]any{"title": nil}
+	empty := map[string]any{"title": ""}
+	valid := map[string]any{"title": "Hello"}
+
+	tests := []struct {
+		name     string
+		input    map[string]any
+		output   any
+		expected any
+		err      error
+	}{
+		{"plain absent", absent, new(Plain), nil, ErrRequiredMissing},
+		{"plain null", null, new(Plain), nil, ErrRequiredMissing},
+		{"plain empty", empty, new(Plain), nil, invalid},
+		{"plain valid", valid, new(Plain), &Plain{"Hello"}, nil},
+
+		{"default absent", absent, new(Defaulted), &Defaulted{"Untitled"}, nil},
+		{"default null", null, new(Defaulted), &Defaulted{"Untitled"}, nil},
+		{"default empty", empty, new(Defaulted), nil, invalid},
+		{"default valid", valid, new(Defaulted), &Defaulted{"Hello"}, nil},
+
+		{"required absent", absent, new(Required), nil, ErrRequiredMissing},
+		{"required null", null, new(Required), nil, ErrRequiredMissing},
+		{"required empty", empty, new(Required), nil, invalid},
+		{"required valid", valid, new(Required), &Required{title("Hello")}, nil},
+
+		{"required default absent", absent, new(RequiredDefaulted), &RequiredDefaulted{title("Untitled")}, nil},
+		{"required default null", null, new(RequiredDefaulted), &RequiredDefaulted{title("Untitled")}, nil},
+		{"required default empty", empty, new(RequiredDefaulted), nil, invalid},
+		{"required default valid", valid, new(RequiredDefaulted), &RequiredDefaulted{title("Hello")}, nil},
+
+		{"omitempty absent", absent, new(OmitEmpty), &OmitEmpty{}, nil},
+		{"omitempty null", null, new(OmitEmpty), nil, ErrRequiredMissing},
+		{"omitempty empty", empty, new(OmitEmpty), nil, invalid},
+		{"omitempty valid", valid, new(OmitEmpty), &OmitEmpty{"Hello"}, nil},
+
+		{"optional absent", absent, new(Optional), &Optional{}, nil},
+		{"optional null", null, new(Optional), &Optional{}, nil},
+		{"optional empty", empty, new(Optional), nil, invalid},
+		{"optional valid", valid, new(Optional), &Optional{title("Hello")}, nil},
+	}
+
+	for _, tt := range tests {
+		err := Parse(tt.input, tt.output)
+
+		switch {
+		case tt.err == invalid:
+			if err == nil || !strings.Contains(err.Error(), invalid.Error()) {
+				t.Errorf("%s: expected the field parser to reject the value, got: %v", tt.name, err)
+			}
+		case tt.err != nil:
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s: expected %v, got: %v", tt.name, tt.err, err)
+			}
+		case err != nil:
+			t.Errorf("%s: Parse returned an error: %v", tt.name, err)
+		case !reflect.DeepEqual(tt.output, tt.expected):
+			t.Errorf("%s: Parse result not as expected.\nGot: %+v\nWant: %+v", tt.name, tt.output, tt.expected)
+		}
+	}
+}
+
+func TestParseDefaultTypes(t *testing.T) {
+	type Server struct {
+		Host    string        `parse:"host,default=localhost"`
+		Port    int           `parse:"port,default=8080"`
+		Debug   bool          `parse:"debug,default=true"`
+		Timeout time.Duration `parse:"timeout,default=5s"`
+		Tags    []string      `parse:"tags,default=web"`
+	}
+
+	var server Server
+	if err := Parse(map[string]any{"port": 9090.0}, &server); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := Server{Host: "localhost", Port: 9090, Debug: true, Timeout: 5 * time.Second, Tags: []string{"web"}}
+	if !reflect.DeepEqual(server, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", server, expected)
+	}
+
+	if err := Parse(map[string]any{"port": "9090"}, &server); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected input values not to be weakly typed like defaults, got: %v", err)
+	}
+
+	type Invalid struct {
+		Port int `parse:"port,default=http"`
+	}
+
+	if err := Parse(map[string]any{}, new(Invalid)); err == nil || !strings.Contains(err.Error(), "port") {
+		t.Errorf("expected an invalid default to be reported for its field, got: %v", err)
+	}
+}
+
+func TestParseNestedLists(t *testing.T) {
+	var points [][3]float64
+	input := []any{[]any{1.0, 2.0, 3.0}, []any{4.0, 5.0}}
+	if err := Parse(input, &points); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if expected := [][3]float64{{1, 2, 3}, {4, 5, 0}}; !reflect.DeepEqual(points, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", points, expected)
+	}
+
+	var columns [2][]string
+	if err := Parse([]any{[]any{"a"}, []any{"b", "c"}}, &columns); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if expected := [2][]string{{"a"}, {"b", "c"}}; !reflect.DeepEqual(columns, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", columns, expected)
+	}
+
+	if err := Parse([][]float64{{7, 8, 9}}, &points); err != nil || points[0] != [3]float64{7, 8, 9} {
+		t.Errorf("expected a typed nested slice to fill a slice of arrays, got: %+v, %v", points, err)
+	}
+
+	err := Parse([]any{[]any{1.0}, []any{1.0, 2.0, 3.0, 4.0}}, &points)
+	if !errors.Is(err, ErrOverflow) || !strings.Contains(err.Error(), "index 1") {
+		t.Errorf("expected an overflowing inner array to be reported at its index, got: %v", err)
+	}
+
+	err = Parse([]any{[]any{"a"}, []any{"b", 1.0}}, &columns)
+	if !errors.Is(err, ErrTypeMismatch) || !strings.Contains(err.Error(), "index 1") {
+		t.Errorf("expected a mismatched inner element to be reported, got: %v", err)
+	}
+
+	merged := [][3]float64{{1, 2, 3}}
+	if err := NewParser(WithMerge(true)).Parse([]any{[]any{9.0}, []any{4.0}}, &merged); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if expected := [][3]float64{{9, 2, 3}, {4, 0, 0}}; !reflect.DeepEqual(merged, expected) {
+		t.Errorf("expected inner arrays to be merged by index.\nGot: %+v\nWant: %+v", merged, expected)
+	}
+
+	copied := [2][]string{{"x"}, {"y"}}
+	var target [2][]string
+	if err := NewParser(WithDeepCopy(true)).Parse(copied, &target); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	target[0][0] = "changed"
+	if copied[0][0] != "x" {
+		t.Errorf("expected DeepCopy to copy the inner slices of an array")
+	}
+}
+
+func TestParserConcurrentUse(t *testing.T) {
+	input := map[string]any{
+		"Title":    "My First Post",
+		"Body":     "This is the content of my first post. It's pretty exciting!",
+		"Metadata": map[string]any{"category": "tech"},
+		"Labels":   []any{"new", "featured"},
+		"Upvotes":  42.0,
+		"Poster": map[string]any{
+			"Username":  "johndoe",
+			"Email":     "john@example.com",
+			"CreatedAt": "2023-09-11T10:00:00Z",
+			"IsAdmin":   true,
+		},
+		"Comments": []any{},
+	}
+
+	var unknown atomic.Int64
+	p := NewParser(WithStdlibHooks(), WithOnUnknownKey(func(path string, value any) {
+		unknown.Add(1)
+	}))
+
+	expected := new(Post)
+	if err := p.Parse(input, expected); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	// ScopedRow registers stdlib hooks through ParseOptions, which must not
+	// write to the hooks the goroutines share
+	row := map[string]any{"Row": map[string]any{"String": "hello"}}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 16)
+	for range 16 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for range 50 {
+				actual := new(Post)
+				if err := p.Parse(input, actual); err != nil {
+					errs <- err
+					return
+				}
+
+				if !reflect.DeepEqual(actual, expected) {
+					errs <- fmt.Errorf("concurrent result not as expected: %+v", actual)
+					return
+				}
+
+				scoped := new(ScopedRow)
+				if err := p.Parse(row, scoped); err != nil || !scoped.Row.String.Valid {
+					errs <- fmt.Errorf("concurrent scoped result not as expected: %+v, %v", scoped, err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
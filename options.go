@@ -0,0 +1,433 @@
+package kaeru
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// DefaultMaxDepth is the nesting limit used when Options.MaxDepth is not set
+const DefaultMaxDepth = 10000
+
+// Options configures the behaviour of a Parser
+type Options struct {
+	// MaxDepth is the maximum nesting of maps, slices and pointers that will
+	// be followed before parsing fails. Guards against stack exhaustion on
+	// untrusted input.
+	MaxDepth int
+
+	// TimeLayout is the layout used to parse string inputs into time.Time
+	// values and types implementing ParseTime. Defaults to time.RFC3339.
+	TimeLayout string
+
+	// TimeLocation is the location assumed for string inputs into times when
+	// the layout has no offset, or the offset does not say which location it
+	// is. Defaults to UTC.
+	TimeLocation *time.Location
+
+	// TruncateArrays drops the trailing elements of an input slice that is
+	// longer than the output array instead of returning an error.
+	TruncateArrays bool
+
+	// Parallelism is the number of goroutines used to parse the elements of
+	// large slices. Values of 1 or less parse sequentially. Custom Parse
+	// methods must be safe for concurrent use when this is enabled.
+	Parallelism int
+
+	// UseNumber decodes JSON numbers as json.Number rather than float64 so
+	// integers beyond 2^53 keep their exact value
+	UseNumber bool
+
+	// Merge parses into existing values instead of replacing them. Keys
+	// absent from the input leave struct fields untouched, maps gain the
+	// input keys and slices and arrays are merged element by element at the
+	// same index, slices growing when the input is longer. Useful for
+	// layering configuration.
+	Merge bool
+
+	// DisallowTypeNarrowing rejects conversions that lose information, such
+	// as a float with a fractional part into an integer or a number that
+	// overflows the output type
+	DisallowTypeNarrowing bool
+
+	// CoerceToString formats numeric and bool inputs with fmt.Sprint and
+	// passes them to ParseString when the output implements no matching
+	// numeric or bool parser
+	CoerceToString bool
+
+	// OnUnknownKey is called for every input key that matches no struct
+	// field and is not collected by a remain field, with the path to the key
+	// such as Comments[0].Extra. Dropped keys are reported, not rejected.
+	OnUnknownKey func(path string, value any)
+
+	// DisallowDuplicateKeys rejects input where more than one key matches the
+	// same field, such as a field name and its alias. By default the first
+	// key present wins.
+	DisallowDuplicateKeys bool
+
+	// KeyTransform rewrites every input key before it is matched against the
+	// fields of a struct, such as stripping a prefix. Field names and then
+	// aliases are looked up using the transformed keys, and the remain field
+	// and OnUnknownKey see the transformed keys too.
+	KeyTransform func(key string) string
+
+	// DeepCopy copies maps, slices and pointers even when the input already
+	// has the output type, so the result never shares memory with the input
+	DeepCopy bool
+
+	// UseSetters fills unexported fields through a pointer method named Set
+	// followed by the capitalized field name, such as SetPort for port. The
+	// method takes the parsed value as its only argument and returns nothing
+	// or an error. Unexported fields without a setter are skipped.
+	UseSetters bool
+
+	// TrimStrings removes leading and trailing whitespace from every string
+	// input, map keys included, before it is parsed or passed to ParseString
+	TrimStrings bool
+
+	// StringTransform normalizes every string input after TrimStrings and
+	// before it is parsed or passed to ParseString
+	StringTransform func(s string) string
+
+	// ExpandEnv replaces ${VAR} and $VAR in every string input with the value
+	// of the environment variable, as os.Expand does, before TrimStrings.
+	// Variables that are not set expand to an empty string.
+	ExpandEnv bool
+
+	// LookupEnv returns the value of a variable for ExpandEnv. Defaults to
+	// os.Getenv, set it to expand from a map or to keep tests apart from the
+	// real environment.
+	LookupEnv func(name string) string
+
+	// CollectErrors keeps parsing the remaining struct fields, map values and
+	// slice elements after one fails, including required values that are
+	// missing, and returns every error found. Each error is reported with
+	// its full location and errors.Is and errors.As see all of them.
+	CollectErrors bool
+
+	// WeaklyTypedInput enables lenient conversions between input and output
+	// kinds, like mapstructure. Numeric and bool strings parse into numbers
+	// and bools, a single character such as ";" parses into a rune when it
+	// is not a digit, numbers and bools format into strings and ParseString as
+	// with CoerceToString, UTF-8 []byte inputs reach ParseString as text,
+	// bools become 1 or 0, numbers become true unless zero, a single value
+	// fills a one element slice and a one element slice fills a single value.
+	WeaklyTypedInput bool
+
+	// NumberBase is the base used to parse strings into integers under
+	// WeaklyTypedInput. Defaults to 10, set 0 to follow the Go literal
+	// syntax so 0x1F, 0b101, 0o17 and 1_000 are accepted.
+	NumberBase int
+
+	// DisallowUnknownKeys rejects input keys that match no struct field and
+	// are not collected by a remain field. Usually set for a single type
+	// through ParseOptions rather than for every struct.
+	DisallowUnknownKeys bool
+
+	// MaxInputSize is the most bytes ParseJson, ParseJsonBytes and ParseFile
+	// accept before failing with ErrInputTooLarge. Zero or less means no
+	// limit.
+	MaxInputSize int64
+
+	// Trace is called as each value starts being parsed and whenever a custom
+	// parser is called for one, to follow how an input maps onto the output.
+	// Parsing does no extra work when it is nil.
+	Trace func(event TraceEvent)
+
+	// RequireCustomParsers fails with ErrMissingParser when a value would be
+	// converted into a named primitive type, such as a string into an Email,
+	// instead of going through a Parse method or hook of that type. Catches
+	// domain types that were never given a validating parser.
+	RequireCustomParsers bool
+
+	// SkipAbsentStructs leaves a struct untouched when its value is absent or
+	// null, or for an embedded struct when none of its keys are present,
+	// instead of descending into its fields. The fields are then neither
+	// required nor given their defaults. Speeds up sparse input into wide
+	// structs.
+	SkipAbsentStructs bool
+
+	// DisallowUnexportedKeys rejects input keys that match an unexported
+	// field, which is otherwise skipped without a word unless UseSetters
+	// finds a setter for it
+	DisallowUnexportedKeys bool
+
+	// The maps below are shared with every copy of Options, such as the one
+	// applyOptions makes for a type implementing ParseOptions. Options that
+	// register into them copy the map first and never write in place, so
+	// scoped registrations cannot leak into the Parser they came from.
+	hooks            map[reflect.Type]hook
+	stdlibInterfaces bool
+	variants         map[reflect.Type]variants
+
+	// formats are the file formats registered with WithFileFormat, and
+	// extensions maps a lower case file extension to the name of its format
+	formats    map[string]fileFormat
+	extensions map[string]string
+
+	// fieldKey normalizes field names and aliases before they are looked up
+	// in the input, used with a matching KeyTransform by ParseHeader
+	fieldKey func(name string) string
+
+	// unwrapSingle parses a one element slice into a non slice output
+	unwrapSingle bool
+}
+
+// Option modifies the Options of a Parser
+type Option func(*Options)
+
+// WithMaxDepth sets Options.MaxDepth
+func WithMaxDepth(depth int) Option {
+	return func(o *Options) {
+		o.MaxDepth = depth
+	}
+}
+
+// WithTimeLayout sets Options.TimeLayout
+func WithTimeLayout(layout string) Option {
+	return func(o *Options) {
+		o.TimeLayout = layout
+	}
+}
+
+// WithTimeLocation sets Options.TimeLocation
+func WithTimeLocation(loc *time.Location) Option {
+	return func(o *Options) {
+		o.TimeLocation = loc
+	}
+}
+
+// WithTruncateArrays sets Options.TruncateArrays
+func WithTruncateArrays(truncate bool) Option {
+	return func(o *Options) {
+		o.TruncateArrays = truncate
+	}
+}
+
+// WithParallelism sets Options.Parallelism
+func WithParallelism(n int) Option {
+	return func(o *Options) {
+		o.Parallelism = n
+	}
+}
+
+// WithUseNumber sets Options.UseNumber
+func WithUseNumber(useNumber bool) Option {
+	return func(o *Options) {
+		o.UseNumber = useNumber
+	}
+}
+
+// WithMerge sets Options.Merge
+func WithMerge(merge bool) Option {
+	return func(o *Options) {
+		o.Merge = merge
+	}
+}
+
+// WithDisallowTypeNarrowing sets Options.DisallowTypeNarrowing
+func WithDisallowTypeNarrowing(disallow bool) Option {
+	return func(o *Options) {
+		o.DisallowTypeNarrowing = disallow
+	}
+}
+
+// WithCoerceToString sets Options.CoerceToString
+func WithCoerceToString(coerce bool) Option {
+	return func(o *Options) {
+		o.CoerceToString = coerce
+	}
+}
+
+// WithOnUnknownKey sets Options.OnUnknownKey
+func WithOnUnknownKey(fn func(path string, value any)) Option {
+	return func(o *Options) {
+		o.OnUnknownKey = fn
+	}
+}
+
+// WithDisallowDuplicateKeys sets Options.DisallowDuplicateKeys
+func WithDisallowDuplicateKeys(disallow bool) Option {
+	return func(o *Options) {
+		o.DisallowDuplicateKeys = disallow
+	}
+}
+
+// WithKeyTransform sets Options.KeyTransform
+func WithKeyTransform(transform func(key string) string) Option {
+	return func(o *Options) {
+		o.KeyTransform = transform
+	}
+}
+
+// WithDeepCopy sets Options.DeepCopy
+func WithDeepCopy(deepCopy bool) Option {
+	return func(o *Options) {
+		o.DeepCopy = deepCopy
+	}
+}
+
+// WithUseSetters sets Options.UseSetters
+func WithUseSetters(useSetters bool) Option {
+	return func(o *Options) {
+		o.UseSetters = useSetters
+	}
+}
+
+// WithTrimStrings sets Options.TrimStrings
+func WithTrimStrings(trim bool) Option {
+	return func(o *Options) {
+		o.TrimStrings = trim
+	}
+}
+
+// WithStringTransform sets Options.StringTransform
+func WithStringTransform(transform func(s string) string) Option {
+	return func(o *Options) {
+		o.StringTransform = transform
+	}
+}
+
+// WithExpandEnv sets Options.ExpandEnv
+func WithExpandEnv(expand bool) Option {
+	return func(o *Options) {
+		o.ExpandEnv = expand
+	}
+}
+
+// WithLookupEnv sets Options.LookupEnv
+func WithLookupEnv(lookup func(name string) string) Option {
+	return func(o *Options) {
+		o.LookupEnv = lookup
+	}
+}
+
+// WithCollectErrors sets Options.CollectErrors
+func WithCollectErrors(collect bool) Option {
+	return func(o *Options) {
+		o.CollectErrors = collect
+	}
+}
+
+// WithWeaklyTypedInput sets Options.WeaklyTypedInput
+func WithWeaklyTypedInput(weak bool) Option {
+	return func(o *Options) {
+		o.WeaklyTypedInput = weak
+	}
+}
+
+// WithNumberBase sets Options.NumberBase
+func WithNumberBase(base int) Option {
+	return func(o *Options) {
+		o.NumberBase = base
+	}
+}
+
+// WithDisallowUnknownKeys sets Options.DisallowUnknownKeys
+func WithDisallowUnknownKeys(disallow bool) Option {
+	return func(o *Options) {
+		o.DisallowUnknownKeys = disallow
+	}
+}
+
+// WithMaxInputSize sets Options.MaxInputSize
+func WithMaxInputSize(n int64) Option {
+	return func(o *Options) {
+		o.MaxInputSize = n
+	}
+}
+
+// WithTrace sets Options.Trace
+func WithTrace(trace func(event TraceEvent)) Option {
+	return func(o *Options) {
+		o.Trace = trace
+	}
+}
+
+// WithRequireCustomParsers sets Options.RequireCustomParsers
+func WithRequireCustomParsers(require bool) Option {
+	return func(o *Options) {
+		o.RequireCustomParsers = require
+	}
+}
+
+// WithSkipAbsentStructs sets Options.SkipAbsentStructs
+func WithSkipAbsentStructs(skip bool) Option {
+	return func(o *Options) {
+		o.SkipAbsentStructs = skip
+	}
+}
+
+// WithDisallowUnexportedKeys sets Options.DisallowUnexportedKeys
+func WithDisallowUnexportedKeys(disallow bool) Option {
+	return func(o *Options) {
+		o.DisallowUnexportedKeys = disallow
+	}
+}
+
+// Parser parses input using a fixed set of Options. The package level
+// functions use a Parser with the default options. A Parser never changes
+// once constructed and is safe for concurrent use, so a single Parser can
+// serve every request of a server. Callbacks such as Trace and OnUnknownKey
+// may then be called from several goroutines at once.
+type Parser struct {
+	opts Options
+
+	// states holds the bookkeeping of finished parses for reuse, cutting
+	// allocations when the same Parser is used repeatedly
+	states sync.Pool
+}
+
+var defaultParser = NewParser()
+
+func NewParser(opts ...Option) *Parser {
+	base := Options{
+		MaxDepth:   DefaultMaxDepth,
+		TimeLayout: time.RFC3339,
+		NumberBase: 10,
+	}
+
+	return &Parser{opts: applyOptions(base, opts)}
+}
+
+// applyOptions returns a copy of base with opts applied, restoring the
+// defaults of settings that were cleared
+func applyOptions(base Options, opts []Option) Options {
+	for _, opt := range opts {
+		opt(&base)
+	}
+
+	if base.MaxDepth <= 0 {
+		base.MaxDepth = DefaultMaxDepth
+	}
+
+	if base.TimeLayout == "" {
+		base.TimeLayout = time.RFC3339
+	}
+
+	return base
+}
+
+func (p *Parser) newState() *state {
+	s, ok := p.states.Get().(*state)
+	if !ok {
+		s = &state{}
+	}
+
+	s.ctx = context.Background()
+	s.opts = &p.opts
+
+	return s
+}
+
+// release resets a state from newState and returns it to the pool
+func (p *Parser) release(s *state) {
+	clear(s.visiting)
+	s.ctx = nil
+	s.depth = 0
+	s.layout = ""
+	s.path = s.path[:0]
+
+	p.states.Put(s)
+}
@@ -0,0 +1,66 @@
+package kaeru
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+)
+
+// parallelThreshold is the minimum slice length parsed concurrently, below it
+// the goroutine overhead outweighs the gain
+const parallelThreshold = 1024
+
+// fork returns a copy of the state that can be used from another goroutine
+func (s *state) fork() *state {
+	return &state{
+		ctx:      s.ctx,
+		opts:     s.opts,
+		depth:    s.depth,
+		visiting: maps.Clone(s.visiting),
+		layout:   s.layout,
+		path:     slices.Clone(s.path),
+	}
+}
+
+// parseElementsParallel parses every element of inVal into outSlice, splitting
+// the work into contiguous chunks across Options.Parallelism goroutines. The
+// error returned is the one for the lowest index, same as parsing in order.
+func (s *state) parseElementsParallel(inVal reflect.Value, outSlice reflect.Value) error {
+	n := inVal.Len()
+	workers := min(s.opts.Parallelism, n)
+	chunk := (n + workers - 1) / workers
+	errs := make([][]error, workers)
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		start := w * chunk
+		end := min(start+chunk, n)
+
+		wg.Add(1)
+		go func(w int, fs *state) {
+			defer wg.Done()
+
+			for i := start; i < end; i++ {
+				if err := fs.parseIndex(i, inVal.Index(i), outSlice.Index(i)); err != nil {
+					errs[w] = append(errs[w], elementError(i, err))
+					if !fs.opts.CollectErrors {
+						return
+					}
+				}
+			}
+		}(w, s.fork())
+	}
+
+	wg.Wait()
+
+	if !s.opts.CollectErrors {
+		for _, workerErrs := range errs {
+			if len(workerErrs) > 0 {
+				return workerErrs[0]
+			}
+		}
+	}
+
+	return joinErrors(slices.Concat(errs...))
+}
@@ -0,0 +1,70 @@
+package kaeru
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func usernameInput(n int) []any {
+	input := make([]any, n)
+	for i := range input {
+		input[i] = fmt.Sprintf("user_%d", i)
+	}
+	return input
+}
+
+func TestParseParallel(t *testing.T) {
+	input := usernameInput(10000)
+
+	expected := new([]Username)
+	if err := Parse(input, expected); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	actual := new([]Username)
+	if err := NewParser(WithParallelism(4)).Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("parallel result differs from sequential result")
+	}
+
+	// The error for the lowest index is reported regardless of scheduling
+	input[9000] = "!"
+	input[5000] = "?"
+	input[100] = "x"
+
+	err := NewParser(WithParallelism(4)).Parse(input, new([]Username))
+	if err == nil || !strings.Contains(err.Error(), "element at index 100: Username") {
+		t.Fatalf("expected username error at index 100, got: %v", err)
+	}
+
+	sequentialErr := Parse(input, new([]Username))
+	if err.Error() != sequentialErr.Error() {
+		t.Errorf("parallel error differs from sequential error.\nGot: %v\nWant: %v", err, sequentialErr)
+	}
+}
+
+func BenchmarkParseSlice(b *testing.B) {
+	input := usernameInput(100000)
+
+	b.Run("sequential", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := Parse(input, new([]Username)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("parallel", func(b *testing.B) {
+		parser := NewParser(WithParallelism(8))
+		for i := 0; i < b.N; i++ {
+			if err := parser.Parse(input, new([]Username)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
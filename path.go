@@ -0,0 +1,68 @@
+package kaeru
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// tracksPath reports whether an option needs the location of each value,
+// the path is not built otherwise to keep parsing cheap
+func (s *state) tracksPath() bool {
+	return s.opts.OnUnknownKey != nil || s.opts.Trace != nil
+}
+
+// parseField parses a struct field or map value, recording key in the path
+func (s *state) parseField(key string, inVal reflect.Value, outVal reflect.Value) error {
+	if !s.tracksPath() {
+		return s.parseValue(inVal, outVal)
+	}
+
+	s.path = append(s.path, key)
+	err := s.parseValue(inVal, outVal)
+	s.path = s.path[:len(s.path)-1]
+
+	return err
+}
+
+// parseMapValue parses a map value, recording its key in the path
+func (s *state) parseMapValue(key reflect.Value, inVal reflect.Value, outVal reflect.Value) error {
+	if !s.tracksPath() {
+		return s.parseValue(inVal, outVal)
+	}
+
+	return s.parseField(fmt.Sprint(key.Interface()), inVal, outVal)
+}
+
+// parseIndex parses a slice or array element, recording its index in the path
+func (s *state) parseIndex(i int, inVal reflect.Value, outVal reflect.Value) error {
+	if !s.tracksPath() {
+		return s.parseValue(inVal, outVal)
+	}
+
+	s.path = append(s.path, "["+strconv.Itoa(i)+"]")
+	err := s.parseValue(inVal, outVal)
+	s.path = s.path[:len(s.path)-1]
+
+	return err
+}
+
+// pathTo formats the path of key below the current value, such as
+// Comments[0].Metadata.likes
+func (s *state) pathTo(key string) string {
+	return formatPath(append(s.path, key))
+}
+
+func formatPath(segments []string) string {
+	var b strings.Builder
+	for _, segment := range segments {
+		if b.Len() > 0 && !strings.HasPrefix(segment, "[") {
+			b.WriteByte('.')
+		}
+
+		b.WriteString(segment)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,51 @@
+package kaeru
+
+import (
+	"reflect"
+)
+
+// isSeq reports whether t has the shape of an iter.Seq, that is
+// func(yield func(V) bool)
+func isSeq(t reflect.Type) bool {
+	if t.Kind() != reflect.Func || t.NumIn() != 1 || t.NumOut() != 0 {
+		return false
+	}
+
+	yield := t.In(0)
+
+	return yield.Kind() == reflect.Func &&
+		yield.NumIn() == 1 &&
+		yield.NumOut() == 1 &&
+		yield.Out(0).Kind() == reflect.Bool
+}
+
+// parseSeq materializes an iter.Seq into the output slice, parsing every
+// yielded element. Iteration stops at the first element that fails to parse.
+// A nil iterator leaves the output slice nil.
+func (s *state) parseSeq(inVal reflect.Value, outVal reflect.Value) error {
+	if outVal.Kind() != reflect.Slice {
+		return mismatch(inVal, outVal)
+	}
+
+	if inVal.IsNil() {
+		outVal.SetZero()
+		return nil
+	}
+
+	outSlice := reflect.MakeSlice(outVal.Type(), 0, 0)
+	elemType := outVal.Type().Elem()
+
+	i := 0
+	for v := range inVal.Seq() {
+		elem := reflect.New(elemType).Elem()
+		if err := s.parseIndex(i, v, elem); err != nil {
+			return elementError(i, err)
+		}
+
+		outSlice = reflect.Append(outSlice, elem)
+		i++
+	}
+
+	outVal.Set(outSlice)
+	return nil
+}
@@ -0,0 +1,50 @@
+package kaeru
+
+import (
+	"iter"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseSeq(t *testing.T) {
+	labels := new([]Label)
+	if err := Parse(slices.Values([]any{"new", "featured"}), labels); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(*labels, []Label{"new", "featured"}) {
+		t.Errorf("Parse result not as expected.\nGot: %v", *labels)
+	}
+
+	ints := new([]int)
+	if err := Parse(slices.Values([]float64{1, 2, 3}), ints); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(*ints, []int{1, 2, 3}) {
+		t.Errorf("Parse result not as expected.\nGot: %v", *ints)
+	}
+
+	yielded := 0
+	var seq iter.Seq[any] = func(yield func(any) bool) {
+		for _, v := range []any{"ok", "", "never"} {
+			yielded++
+			if !yield(v) {
+				return
+			}
+		}
+	}
+
+	if err := Parse(seq, new([]Label)); err == nil {
+		t.Errorf("expected error for invalid label")
+	}
+
+	if yielded != 2 {
+		t.Errorf("expected iteration to stop at the invalid element, yielded %d", yielded)
+	}
+
+	if err := Parse(seq, new(map[string]any)); err == nil {
+		t.Errorf("expected error for non slice output")
+	}
+}
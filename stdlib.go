@@ -0,0 +1,230 @@
+package kaeru
+
+import (
+	"database/sql"
+	"encoding"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"math"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+)
+
+// hook parses inVal into outVal for a type that cannot implement the Parse
+// interfaces itself
+type hook struct {
+	parse func(s *state, inVal reflect.Value, outVal reflect.Value) error
+
+	// nullable hooks are also called for nil input, with an invalid inVal.
+	// Other hooks leave nil input to the usual default and required handling.
+	nullable bool
+}
+
+var stdlibHooks = map[reflect.Type]hook{
+	reflect.TypeFor[sql.NullString]():  {parse: parseSQLNull, nullable: true},
+	reflect.TypeFor[sql.NullInt64]():   {parse: parseSQLNull, nullable: true},
+	reflect.TypeFor[sql.NullInt32]():   {parse: parseSQLNull, nullable: true},
+	reflect.TypeFor[sql.NullInt16]():   {parse: parseSQLNull, nullable: true},
+	reflect.TypeFor[sql.NullByte]():    {parse: parseSQLNull, nullable: true},
+	reflect.TypeFor[sql.NullFloat64](): {parse: parseSQLNull, nullable: true},
+	reflect.TypeFor[sql.NullBool]():    {parse: parseSQLNull, nullable: true},
+	reflect.TypeFor[sql.NullTime]():    {parse: parseSQLNull, nullable: true},
+	reflect.TypeFor[big.Int]():         {parse: parseBigInt},
+	reflect.TypeFor[big.Float]():       {parse: parseBigFloat},
+	reflect.TypeFor[big.Rat]():         {parse: parseBigRat},
+	reflect.TypeFor[net.IP]():          {parse: parseText(parseIP)},
+	reflect.TypeFor[netip.Addr]():      {parse: parseText(netip.ParseAddr)},
+	reflect.TypeFor[url.URL]():         {parse: parseText(parseURL)},
+}
+
+// WithStdlibHooks enables built in parsing for standard library types that
+// do not implement the Parse interfaces, such as the sql.Null* types,
+// big.Int, big.Float, big.Rat, net.IP, netip.Addr and url.URL, and for
+// types implementing encoding.BinaryUnmarshaler
+func WithStdlibHooks() Option {
+	return func(o *Options) {
+		o.stdlibInterfaces = true
+
+		hooks := make(map[reflect.Type]hook, len(o.hooks)+len(stdlibHooks))
+		maps.Copy(hooks, o.hooks)
+		maps.Copy(hooks, stdlibHooks)
+		o.hooks = hooks
+	}
+}
+
+// parseSQLNull fills the value field of a sql.Null* type and marks it valid,
+// leaving it invalid when the input is nil
+func parseSQLNull(s *state, inVal reflect.Value, outVal reflect.Value) error {
+	outVal.Set(reflect.Zero(outVal.Type()))
+
+	if !inVal.IsValid() {
+		return nil
+	}
+
+	if err := s.parseValue(inVal, outVal.Field(0)); err != nil {
+		return err
+	}
+
+	outVal.FieldByName("Valid").SetBool(true)
+
+	return nil
+}
+
+// parseBinary calls UnmarshalBinary on outVal when the input is a []byte or a
+// base64 encoded string. A string is left to ParseString or the time layout
+// when those apply.
+func parseBinary(inVal reflect.Value, outVal reflect.Value) (bool, error) {
+	unmarshaler, ok := outVal.Addr().Interface().(encoding.BinaryUnmarshaler)
+	if !ok {
+		return false, nil
+	}
+
+	switch v := inVal.Interface().(type) {
+	case []byte:
+		return true, unmarshaler.UnmarshalBinary(v)
+	case string:
+		if _, ok := outVal.Addr().Interface().(ParseString); ok || isTime(outVal) {
+			return false, nil
+		}
+
+		data, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return true, fmt.Errorf("invalid base64 for %s: %w", outVal.Type(), err)
+		}
+
+		return true, unmarshaler.UnmarshalBinary(data)
+	}
+
+	return false, nil
+}
+
+// parseBigInt accepts integer strings and numbers without a fractional part
+func parseBigInt(s *state, inVal reflect.Value, outVal reflect.Value) error {
+	out := outVal.Addr().Interface().(*big.Int)
+
+	switch inVal.Kind() {
+	case reflect.String:
+		if _, ok := out.SetString(inVal.String(), 10); !ok {
+			return fmt.Errorf("%w: invalid integer %q", ErrTypeMismatch, inVal.String())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		out.SetInt64(inVal.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		out.SetUint64(inVal.Uint())
+	case reflect.Float32, reflect.Float64:
+		f := inVal.Float()
+		if math.IsInf(f, 0) || math.IsNaN(f) || f != math.Trunc(f) {
+			return fmt.Errorf("%w: %v is not an integer", ErrTypeMismatch, f)
+		}
+
+		big.NewFloat(f).Int(out)
+	default:
+		return mismatch(inVal, outVal)
+	}
+
+	return nil
+}
+
+// parseBigFloat accepts decimal strings and numbers. Strings get enough
+// precision to hold every digit they contain.
+func parseBigFloat(s *state, inVal reflect.Value, outVal reflect.Value) error {
+	out := outVal.Addr().Interface().(*big.Float)
+
+	switch inVal.Kind() {
+	case reflect.String:
+		str := inVal.String()
+		prec := max(64, uint(math.Ceil(float64(len(str))*math.Log2(10))))
+		f, _, err := big.ParseFloat(str, 10, prec, big.ToNearestEven)
+		if err != nil {
+			return fmt.Errorf("%w: invalid decimal %q: %w", ErrTypeMismatch, str, err)
+		}
+
+		out.Set(f)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		out.SetInt64(inVal.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		out.SetUint64(inVal.Uint())
+	case reflect.Float32, reflect.Float64:
+		if math.IsNaN(inVal.Float()) {
+			return fmt.Errorf("%w: NaN is not parseable to %s", ErrTypeMismatch, outVal.Type())
+		}
+
+		out.SetFloat64(inVal.Float())
+	default:
+		return mismatch(inVal, outVal)
+	}
+
+	return nil
+}
+
+// parseBigRat accepts decimal and fraction strings such as "0.1" and "1/3",
+// which are exact, and numbers. A float input is taken as the exact binary
+// value it holds, so strings or json.Number should be used for decimals.
+func parseBigRat(s *state, inVal reflect.Value, outVal reflect.Value) error {
+	out := outVal.Addr().Interface().(*big.Rat)
+
+	switch inVal.Kind() {
+	case reflect.String:
+		if _, ok := out.SetString(inVal.String()); !ok {
+			return fmt.Errorf("%w: invalid rational %q", ErrTypeMismatch, inVal.String())
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		out.SetInt64(inVal.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		out.SetUint64(inVal.Uint())
+	case reflect.Float32, reflect.Float64:
+		if out.SetFloat64(inVal.Float()) == nil {
+			return fmt.Errorf("%w: %v is not parseable to %s", ErrTypeMismatch, inVal.Float(), outVal.Type())
+		}
+	default:
+		return mismatch(inVal, outVal)
+	}
+
+	return nil
+}
+
+// parseText returns a hook that parses string inputs with parse. Inputs that
+// already have the output type are set as is.
+func parseText[T any](parse func(string) (T, error)) func(*state, reflect.Value, reflect.Value) error {
+	return func(s *state, inVal reflect.Value, outVal reflect.Value) error {
+		if inVal.Type() == outVal.Type() {
+			outVal.Set(inVal)
+			return nil
+		}
+
+		if inVal.Kind() != reflect.String {
+			return mismatch(inVal, outVal)
+		}
+
+		v, err := parse(inVal.String())
+		if err != nil {
+			return fmt.Errorf("%w: %w", ErrTypeMismatch, err)
+		}
+
+		outVal.Set(reflect.ValueOf(v))
+		return nil
+	}
+}
+
+// parseIP accepts IPv4 and IPv6 addresses
+func parseIP(str string) (net.IP, error) {
+	ip := net.ParseIP(str)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IP address %q", str)
+	}
+
+	return ip, nil
+}
+
+func parseURL(str string) (url.URL, error) {
+	u, err := url.Parse(str)
+	if err != nil {
+		return url.URL{}, err
+	}
+
+	return *u, nil
+}
@@ -0,0 +1,340 @@
+package kaeru
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"maps"
+	"math"
+	"math/big"
+	"net"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+type Row struct {
+	String  sql.NullString
+	Int64   sql.NullInt64
+	Int32   sql.NullInt32
+	Int16   sql.NullInt16
+	Byte    sql.NullByte
+	Float64 sql.NullFloat64
+	Bool    sql.NullBool
+	Time    sql.NullTime
+}
+
+func TestParseSQLNull(t *testing.T) {
+	parser := NewParser(WithStdlibHooks())
+	createdAt := time.Date(2023, 9, 11, 10, 0, 0, 0, time.UTC)
+
+	input := map[string]any{
+		"String":  "hello",
+		"Int64":   64.0,
+		"Int32":   32.0,
+		"Int16":   16.0,
+		"Byte":    8.0,
+		"Float64": 1.5,
+		"Bool":    false,
+		"Time":    "2023-09-11T10:00:00Z",
+	}
+
+	expected := &Row{
+		String:  sql.NullString{String: "hello", Valid: true},
+		Int64:   sql.NullInt64{Int64: 64, Valid: true},
+		Int32:   sql.NullInt32{Int32: 32, Valid: true},
+		Int16:   sql.NullInt16{Int16: 16, Valid: true},
+		Byte:    sql.NullByte{Byte: 8, Valid: true},
+		Float64: sql.NullFloat64{Float64: 1.5, Valid: true},
+		Bool:    sql.NullBool{Bool: false, Valid: true},
+		Time:    sql.NullTime{Time: createdAt, Valid: true},
+	}
+
+	actual := new(Row)
+	if err := parser.Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	nulls := map[string]any{
+		"String":  nil,
+		"Int64":   nil,
+		"Int32":   nil,
+		"Int16":   nil,
+		"Byte":    nil,
+		"Float64": nil,
+		"Bool":    nil,
+		"Time":    nil,
+	}
+
+	// Previously valid values must be reset by a null input
+	if err := parser.Parse(nulls, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(actual, &Row{}) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", actual, &Row{})
+	}
+
+	if err := Parse(input, new(Row)); err == nil {
+		t.Errorf("expected error parsing sql.Null* types without stdlib hooks")
+	}
+}
+
+type ScopedRow struct {
+	Row Row
+}
+
+func (r *ScopedRow) ParseOptions() []Option {
+	return []Option{WithStdlibHooks()}
+}
+
+func TestParseScopedStdlibHooks(t *testing.T) {
+	// A Parser with hooks of its own shares them with the scoped options
+	parser := NewParser(WithEnum(map[string]Status{"active": Active}))
+	input := map[string]any{"String": "hello", "Int64": 1.0, "Int32": 1.0, "Int16": 1.0, "Byte": 1.0, "Float64": 1.0, "Bool": true, "Time": nil}
+
+	actual := new(ScopedRow)
+	if err := parser.Parse(map[string]any{"Row": input}, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Row.String != (sql.NullString{String: "hello", Valid: true}) {
+		t.Errorf("expected scoped stdlib hooks to parse sql.NullString, got: %+v", actual.Row.String)
+	}
+
+	if err := parser.Parse(input, new(Row)); err == nil {
+		t.Errorf("expected stdlib hooks scoped to ScopedRow to leave the Parser unchanged")
+	}
+}
+
+type Checksum [4]byte
+
+func (c *Checksum) UnmarshalBinary(data []byte) error {
+	if len(data) != len(c) {
+		return errors.New("Checksum must be exactly 4 bytes")
+	}
+
+	copy(c[:], data)
+
+	return nil
+}
+
+func TestParseBinaryUnmarshaler(t *testing.T) {
+	parser := NewParser(WithStdlibHooks())
+	expected := Checksum{1, 2, 3, 4}
+
+	for _, input := range []any{[]byte{1, 2, 3, 4}, "AQIDBA=="} {
+		actual := new(Checksum)
+		if err := parser.Parse(input, actual); err != nil {
+			t.Fatalf("Parse returned an error: %v", err)
+		}
+
+		if *actual != expected {
+			t.Errorf("Parse result not as expected.\nGot: %v\nWant: %v", *actual, expected)
+		}
+	}
+
+	if err := parser.Parse([]byte{1, 2}, new(Checksum)); err == nil {
+		t.Errorf("expected UnmarshalBinary error for short input")
+	}
+
+	if err := parser.Parse("not base64!", new(Checksum)); err == nil {
+		t.Errorf("expected error for invalid base64")
+	}
+
+	if err := Parse("AQIDBA==", new(Checksum)); err == nil {
+		t.Errorf("expected error without stdlib hooks")
+	}
+}
+
+type Ledger struct {
+	Balance *big.Int
+	Supply  big.Int
+	Rate    *big.Float
+}
+
+func TestParseBig(t *testing.T) {
+	parser := NewParser(WithStdlibHooks())
+
+	input := map[string]any{
+		"Balance": "123456789012345678901234567890",
+		"Supply":  json.Number("-98765432109876543210987654321"),
+		"Rate":    "1.000000000000000000000000000001",
+	}
+
+	actual := new(Ledger)
+	if err := parser.Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Balance.String() != "123456789012345678901234567890" {
+		t.Errorf("Balance not as expected, got %s", actual.Balance)
+	}
+
+	if actual.Supply.String() != "-98765432109876543210987654321" {
+		t.Errorf("Supply not as expected, got %s", &actual.Supply)
+	}
+
+	if actual.Rate.Text('f', 30) != "1.000000000000000000000000000001" {
+		t.Errorf("Rate not as expected, got %s", actual.Rate.Text('f', 30))
+	}
+
+	input = map[string]any{"Balance": 42.0, "Supply": int64(7), "Rate": 0.5}
+	actual = new(Ledger)
+	if err := parser.Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Balance.Int64() != 42 || actual.Supply.Int64() != 7 {
+		t.Errorf("Parse result not as expected, got %s and %s", actual.Balance, &actual.Supply)
+	}
+
+	if f, _ := actual.Rate.Float64(); f != 0.5 {
+		t.Errorf("Rate not as expected, got %v", f)
+	}
+
+	invalid := []struct {
+		input map[string]any
+		err   error
+	}{
+		{map[string]any{"Supply": 1.0, "Balance": 4.2}, ErrTypeMismatch},
+		{map[string]any{"Supply": "12abc"}, ErrTypeMismatch},
+		{map[string]any{"Supply": 1.0, "Rate": "one"}, ErrTypeMismatch},
+		{map[string]any{"Supply": 1.0, "Rate": math.NaN()}, ErrTypeMismatch},
+		{map[string]any{"Balance": 1.0}, ErrRequiredMissing},
+	}
+
+	for _, c := range invalid {
+		if err := parser.Parse(c.input, new(Ledger)); !errors.Is(err, c.err) {
+			t.Errorf("expected %v parsing %v, got: %v", c.err, c.input, err)
+		}
+	}
+}
+
+// Decimal stands in for a third party decimal type, which plugs in through
+// ParseString so decimal strings never go through float64
+type Decimal struct {
+	units int64
+	scale int
+}
+
+func (d *Decimal) ParseString(s string) error {
+	whole, frac, _ := strings.Cut(s, ".")
+	units, err := strconv.ParseInt(whole+frac, 10, 64)
+	if err != nil {
+		return err
+	}
+
+	*d = Decimal{units, len(frac)}
+	return nil
+}
+
+type Invoice struct {
+	Total Decimal
+	Ratio *big.Rat
+	Tax   big.Rat
+}
+
+func TestParseDecimals(t *testing.T) {
+	parser := NewParser(WithStdlibHooks(), WithUseNumber(true))
+
+	actual := new(Invoice)
+	err := parser.ParseJsonBytes([]byte(`{"Total": 0.1, "Ratio": "1/3", "Tax": 0.07}`), actual)
+	if err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Total != (Decimal{1, 1}) {
+		t.Errorf("expected json.Number to reach ParseString exactly, got %+v", actual.Total)
+	}
+
+	if actual.Ratio.String() != "1/3" || actual.Tax.String() != "7/100" {
+		t.Errorf("big.Rat not as expected, got %s and %s", actual.Ratio, &actual.Tax)
+	}
+
+	actual = new(Invoice)
+	if err := parser.Parse(map[string]any{"Total": "12.34", "Ratio": 2, "Tax": 0.5}, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Total != (Decimal{1234, 2}) || actual.Ratio.String() != "2/1" || actual.Tax.String() != "1/2" {
+		t.Errorf("Parse result not as expected, got %+v %s %s", actual.Total, actual.Ratio, &actual.Tax)
+	}
+
+	if err := parser.Parse(map[string]any{"Total": "1", "Ratio": "1/0", "Tax": 1}, new(Invoice)); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected type mismatch for an invalid rational, got: %v", err)
+	}
+
+	if err := parser.Parse(map[string]any{"Total": "1", "Ratio": 1, "Tax": math.Inf(1)}, new(Invoice)); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected type mismatch for an infinite rational, got: %v", err)
+	}
+}
+
+type Upstream struct {
+	Bind     net.IP
+	Peers    []netip.Addr
+	Endpoint *url.URL
+	Health   url.URL
+}
+
+func TestParseNetworkTypes(t *testing.T) {
+	parser := NewParser(WithStdlibHooks())
+
+	input := map[string]any{
+		"Bind":     "192.168.1.10",
+		"Peers":    []any{"10.0.0.1", "2001:db8::1"},
+		"Endpoint": "https://example.com:8443/api?v=2",
+		"Health":   "/healthz",
+	}
+
+	actual := new(Upstream)
+	if err := parser.Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !actual.Bind.Equal(net.IPv4(192, 168, 1, 10)) {
+		t.Errorf("expected an IPv4 address, got: %v", actual.Bind)
+	}
+
+	expectedPeers := []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("2001:db8::1")}
+	if !reflect.DeepEqual(actual.Peers, expectedPeers) || !actual.Peers[1].Is6() {
+		t.Errorf("expected IPv4 and IPv6 peers, got: %v", actual.Peers)
+	}
+
+	if actual.Endpoint == nil || actual.Endpoint.Host != "example.com:8443" || actual.Endpoint.Query().Get("v") != "2" {
+		t.Errorf("expected the endpoint URL to be parsed, got: %v", actual.Endpoint)
+	}
+
+	if actual.Health.Path != "/healthz" {
+		t.Errorf("expected a relative URL, got: %v", actual.Health)
+	}
+
+	input["Bind"] = "::1"
+	if err := parser.Parse(input, actual); err != nil || !actual.Bind.IsLoopback() || actual.Bind.To4() != nil {
+		t.Errorf("expected an IPv6 loopback address, got: %v, %v", actual.Bind, err)
+	}
+
+	invalid := []map[string]any{
+		{"Bind": "256.0.0.1"},
+		{"Peers": []any{"10.0.0"}},
+		{"Endpoint": "http://[::1"},
+		{"Bind": 42},
+	}
+
+	for _, fields := range invalid {
+		bad := maps.Clone(input)
+		maps.Copy(bad, fields)
+
+		if err := parser.Parse(bad, new(Upstream)); !errors.Is(err, ErrTypeMismatch) {
+			t.Errorf("expected type mismatch for %v, got: %v", fields, err)
+		}
+	}
+}
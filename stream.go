@@ -0,0 +1,87 @@
+package kaeru
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// ParseJsonStream decodes a JSON array from r one element at a time. Each
+// element is parsed into elemPtr, which is reset to its zero value first, and
+// fn is called before moving on to the next element. Returning an error from
+// fn stops decoding and returns that error. Memory use stays flat regardless
+// of the length of the array.
+func ParseJsonStream(r io.Reader, elemPtr any, fn func() error) error {
+	return defaultParser.ParseJsonStream(r, elemPtr, fn)
+}
+
+func (p *Parser) ParseJsonStream(r io.Reader, elemPtr any, fn func() error) error {
+	elemVal := reflect.ValueOf(elemPtr)
+	if elemVal.Kind() != reflect.Pointer {
+		return fmt.Errorf("%w: elemPtr must be a pointer", ErrOutputNotPointer)
+	}
+
+	if elemVal.IsNil() {
+		return fmt.Errorf("%w: elemPtr %s", ErrNilOutput, elemVal.Type())
+	}
+	elemVal = elemVal.Elem()
+
+	decoder := json.NewDecoder(r)
+	if p.opts.UseNumber {
+		decoder.UseNumber()
+	}
+
+	if err := expectDelim(decoder, '['); err != nil {
+		return err
+	}
+
+	for i := 0; decoder.More(); i++ {
+		var v any
+		if err := decoder.Decode(&v); err != nil {
+			return fmt.Errorf("error decoding element at index %d: %w", i, err)
+		}
+
+		elemVal.Set(reflect.Zero(elemVal.Type()))
+
+		if err := p.Parse(v, elemPtr); err != nil {
+			return fmt.Errorf("error parsing element at index %d: %w", i, err)
+		}
+
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+
+	if err := expectDelim(decoder, ']'); err != nil {
+		return err
+	}
+
+	// Only whitespace may follow the array
+	if _, err := decoder.Token(); err != io.EOF {
+		if err == nil {
+			return errors.New("invalid data after top-level array")
+		}
+		return err
+	}
+
+	return nil
+}
+
+func expectDelim(decoder *json.Decoder, delim json.Delim) error {
+	token, err := decoder.Token()
+	if err == io.EOF {
+		return fmt.Errorf("unexpected end of input, expected %s", delim)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if token != delim {
+		return fmt.Errorf("unexpected %v, expected %s", token, delim)
+	}
+
+	return nil
+}
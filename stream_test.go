@@ -0,0 +1,64 @@
+package kaeru
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseJsonStream(t *testing.T) {
+	data := `[
+		{"Username": "johndoe", "Email": "john@example.com", "CreatedAt": "2023-09-11T10:00:00Z", "IsAdmin": true},
+		{"Username": "janedoe", "Email": "jane@example.com", "CreatedAt": "2023-09-10T09:00:00Z", "IsAdmin": false}
+	]`
+
+	var usernames []Username
+	user := new(User)
+
+	err := ParseJsonStream(strings.NewReader(data), user, func() error {
+		usernames = append(usernames, user.Username)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("ParseJsonStream returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(usernames, []Username{"johndoe", "janedoe"}) {
+		t.Errorf("ParseJsonStream result not as expected.\nGot: %v", usernames)
+	}
+
+	stop := errors.New("stop")
+	calls := 0
+	err = ParseJsonStream(strings.NewReader(data), user, func() error {
+		calls++
+		return stop
+	})
+	if !errors.Is(err, stop) || calls != 1 {
+		t.Errorf("expected callback error to stop decoding, got %v after %d calls", err, calls)
+	}
+
+	invalid := []string{
+		``,
+		`{"Username": "johndoe"}`,
+		`[{"Username": "!"}]`,
+		`[1, 2`,
+		`[] []`,
+		`[}`,
+	}
+
+	for _, data := range invalid {
+		err := ParseJsonStream(strings.NewReader(data), user, func() error { return nil })
+		if err == nil {
+			t.Errorf("expected error for %q", data)
+		}
+	}
+
+	if err := ParseJsonStream(strings.NewReader(`[]`), User{}, func() error { return nil }); !errors.Is(err, ErrOutputNotPointer) {
+		t.Errorf("expected error for non pointer element, got: %v", err)
+	}
+
+	if err := ParseJsonStream(strings.NewReader(`[]`), (*User)(nil), func() error { return nil }); !errors.Is(err, ErrNilOutput) {
+		t.Errorf("expected error for nil element, got: %v", err)
+	}
+}
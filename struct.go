@@ -0,0 +1,316 @@
+package kaeru
+
+import (
+	"database/sql/driver"
+	"encoding"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// parseStruct parses a struct input by treating its exported fields as a map
+// keyed by their parse names, so every map to struct feature applies.
+// Identical struct types never get here as they are set directly.
+func (s *state) parseStruct(inVal reflect.Value, outVal reflect.Value) error {
+	if inVal.Type() == timeType {
+		return mismatch(inVal, outVal)
+	}
+
+	if outVal.Kind() == reflect.Map && outVal.Type().Elem().Kind() == reflect.Interface {
+		return s.parseStructToMap(inVal, outVal)
+	}
+
+	return s.parseMap(reflect.ValueOf(structToMap(inVal)), outVal)
+}
+
+// parseStructToMap parses a struct input into a map of interface values.
+// Nested structs, including those in slices, arrays and maps, become
+// map[string]any too so the result is a generic tree. Structs with a form
+// of their own, such as times and types implementing fmt.Stringer, are kept
+// as they are.
+func (s *state) parseStructToMap(inVal reflect.Value, outVal reflect.Value) error {
+	generic, err := s.genericStruct(inVal)
+	if err != nil {
+		return err
+	}
+
+	return s.parseMap(reflect.ValueOf(generic), outVal)
+}
+
+func (s *state) genericStruct(inVal reflect.Value) (map[string]any, error) {
+	out := structToMap(inVal)
+	for key, value := range out {
+		generic, err := s.generic(reflect.ValueOf(value))
+		if err != nil {
+			return nil, fmt.Errorf("error parsing field %s: %w", key, err)
+		}
+
+		out[key] = generic
+	}
+
+	return out, nil
+}
+
+// generic returns the value of v with the structs it holds replaced by maps
+func (s *state) generic(v reflect.Value) (any, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+
+	if !holdsStructs(v.Type()) {
+		return v.Interface(), nil
+	}
+
+	if err := s.enter(); err != nil {
+		return nil, err
+	}
+	defer s.leave()
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		key, err := s.push(v)
+		if err != nil {
+			return nil, err
+		}
+		defer s.pop(key)
+
+		return s.generic(v.Elem())
+	case reflect.Struct:
+		return s.genericStruct(v)
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil, nil
+		}
+
+		out := make([]any, v.Len())
+		for i := range out {
+			elem, err := s.generic(v.Index(i))
+			if err != nil {
+				return nil, elementError(i, err)
+			}
+
+			out[i] = elem
+		}
+
+		return out, nil
+	case reflect.Map:
+		if v.IsNil() {
+			return nil, nil
+		}
+
+		out := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			key := fmt.Sprint(iter.Key().Interface())
+
+			value, err := s.generic(iter.Value())
+			if err != nil {
+				return nil, fmt.Errorf("error parsing map value %s: %w", key, err)
+			}
+
+			out[key] = value
+		}
+
+		return out, nil
+	default:
+		return v.Interface(), nil
+	}
+}
+
+var (
+	formatType        = reflect.TypeFor[Format]()
+	valuerType        = reflect.TypeFor[driver.Valuer]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+	stringerType      = reflect.TypeFor[fmt.Stringer]()
+)
+
+// holdsStructs reports whether values of type t are or contain structs that
+// generic turns into maps
+func holdsStructs(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return holdsStructs(t.Elem())
+	case reflect.Interface:
+		return true
+	case reflect.Struct:
+		return t != timeType && hasExportedFields(t) && !hasOwnForm(t)
+	default:
+		return false
+	}
+}
+
+// hasOwnForm reports whether t or *t implements an interface Unparse uses
+// instead of the fields of a struct
+func hasOwnForm(t reflect.Type) bool {
+	for _, iface := range []reflect.Type{formatType, valuerType, textMarshalerType, stringerType} {
+		if t.Implements(iface) || reflect.PointerTo(t).Implements(iface) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// structToMap returns the exported fields of inVal keyed by their parse names,
+// with the fields of untagged embedded structs promoted
+func structToMap(inVal reflect.Value) map[string]any {
+	out := make(map[string]any, inVal.NumField())
+	addFields(inVal, out, shadowedFields(inVal.Type()), 0)
+
+	return out
+}
+
+func addFields(inVal reflect.Value, out map[string]any, hidden shadowed, depth int) {
+	inType := inVal.Type()
+
+	for i := 0; i < inVal.NumField(); i++ {
+		fieldType := inType.Field(i)
+
+		tag := fieldType.Tag.Get("parse")
+		if tag == "-" {
+			continue
+		}
+
+		fieldName := fieldType.Name
+		name, _ := parseTag(tag)
+		if name != "" {
+			fieldName = name
+		}
+
+		if !fieldType.IsExported() && (name != "" || !isEmbeddedStruct(fieldType)) {
+			continue
+		}
+
+		if name == "" && isEmbeddedStruct(fieldType) {
+			field := inVal.Field(i)
+			if field.Kind() == reflect.Pointer {
+				if field.IsNil() {
+					continue
+				}
+
+				field = field.Elem()
+			}
+
+			addFields(field, out, hidden, depth+1)
+			continue
+		}
+
+		if hidden.hides(fieldName, depth) {
+			continue
+		}
+
+		out[fieldName] = inVal.Field(i).Interface()
+	}
+}
+
+// shadowKey is the name of a field at a depth of embedded structs, 0 being
+// the fields of the struct itself
+type shadowKey struct {
+	name  string
+	depth int
+}
+
+// shadowed holds the fields hidden by the Go and encoding/json rules for
+// promoted fields
+type shadowed map[shadowKey]struct{}
+
+func (h shadowed) hides(name string, depth int) bool {
+	_, ok := h[shadowKey{name, depth}]
+	return ok
+}
+
+// shadowCache holds the result of shadowedFields by struct type, as it only
+// depends on the type
+var shadowCache sync.Map
+
+// shadowedFields returns the fields of t that are not promoted under their
+// name: a field loses to one of the same name at a shallower depth, and
+// fields sharing the shallowest depth of their name are all dropped. It is
+// nil when t embeds no structs, so nothing can be shadowed.
+func shadowedFields(t reflect.Type) shadowed {
+	embeds := false
+	for i := 0; i < t.NumField(); i++ {
+		if isEmbeddedStruct(t.Field(i)) {
+			embeds = true
+			break
+		}
+	}
+
+	if !embeds {
+		return nil
+	}
+
+	if cached, ok := shadowCache.Load(t); ok {
+		return cached.(shadowed)
+	}
+
+	counts := make(map[shadowKey]int)
+	shallowest := make(map[string]int)
+	countFields(t, 0, counts, shallowest, map[reflect.Type]bool{})
+
+	var hidden shadowed
+	for key, count := range counts {
+		if key.depth > shallowest[key.name] || count > 1 {
+			if hidden == nil {
+				hidden = make(shadowed)
+			}
+
+			hidden[key] = struct{}{}
+		}
+	}
+
+	shadowCache.Store(t, hidden)
+	return hidden
+}
+
+// countFields counts the field names of t and its embedded structs by depth,
+// skipping a struct already embedded on the way to it so embedding cycles
+// through pointers end
+func countFields(t reflect.Type, depth int, counts map[shadowKey]int, shallowest map[string]int, path map[reflect.Type]bool) {
+	path[t] = true
+	defer delete(path, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+
+		tag := fieldType.Tag.Get("parse")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := parseTag(tag)
+		if opts.Has("remain") || opts.Has("inline") {
+			continue
+		}
+
+		if name == "" && isEmbeddedStruct(fieldType) {
+			elem := fieldType.Type
+			if elem.Kind() == reflect.Pointer {
+				elem = elem.Elem()
+			}
+
+			if !path[elem] {
+				countFields(elem, depth+1, counts, shallowest, path)
+			}
+
+			continue
+		}
+
+		if !fieldType.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = fieldType.Name
+		}
+
+		counts[shadowKey{name, depth}]++
+		if d, ok := shallowest[name]; !ok || depth < d {
+			shallowest[name] = depth
+		}
+	}
+}
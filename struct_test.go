@@ -0,0 +1,531 @@
+package kaeru
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type UserRecord struct {
+	Username  string
+	Email     string
+	CreatedAt time.Time
+	IsAdmin   bool
+	internal  int
+}
+
+type Account struct {
+	Username  Username
+	Email     Email
+	CreatedAt time.Time
+	IsAdmin   bool
+}
+
+func TestParseStructInput(t *testing.T) {
+	createdAt := time.Date(2023, 9, 11, 10, 0, 0, 0, time.UTC)
+	input := UserRecord{"johndoe", "john@example.com", createdAt, true, 1}
+
+	actual := new(Account)
+	if err := Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := &Account{"johndoe", "john@example.com", createdAt, true}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	input.Email = "invalid"
+	if err := Parse(input, new(Account)); err == nil {
+		t.Errorf("expected Email validation error for struct input")
+	}
+
+	same := new(UserRecord)
+	if err := Parse(input, same); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(*same, input) {
+		t.Errorf("identical struct not copied as is.\nGot: %+v\nWant: %+v", *same, input)
+	}
+}
+
+type deepNode struct {
+	Name     string
+	Weight   float64
+	Children *[]deepNode
+}
+
+func deepTree(depth int, width int) deepNode {
+	node := deepNode{Name: "node", Weight: 1.5}
+	if depth > 0 {
+		children := make([]deepNode, width)
+		for i := range children {
+			children[i] = deepTree(depth-1, width)
+		}
+		node.Children = &children
+	}
+	return node
+}
+
+func BenchmarkParseIdenticalStruct(b *testing.B) {
+	tree := deepTree(6, 3)
+
+	generic, err := Unparse(tree)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("identical", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := Parse(tree, new(deepNode)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+
+	b.Run("recursive", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if err := Parse(generic, new(deepNode)); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+type Timestamps struct {
+	CreatedAt time.Time
+	UpdatedAt time.Time `parse:"updated_at"`
+}
+
+type Identity struct {
+	ID int
+}
+
+type Document struct {
+	Identity
+	*Timestamps
+	Title string
+}
+
+func TestParseEmbeddedStruct(t *testing.T) {
+	createdAt := time.Date(2023, 9, 11, 10, 0, 0, 0, time.UTC)
+	updatedAt := time.Date(2023, 9, 12, 10, 0, 0, 0, time.UTC)
+
+	actual := new(Document)
+	err := Parse(map[string]any{
+		"ID":         7,
+		"Title":      "Notes",
+		"CreatedAt":  "2023-09-11T10:00:00Z",
+		"updated_at": "2023-09-12T10:00:00Z",
+	}, actual)
+	if err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := &Document{Identity{7}, &Timestamps{createdAt, updatedAt}, "Notes"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	unparsed, err := Unparse(expected)
+	if err != nil {
+		t.Fatalf("Unparse returned an error: %v", err)
+	}
+
+	roundTrip := new(Document)
+	if err := Parse(unparsed, roundTrip); err != nil || !reflect.DeepEqual(roundTrip, expected) {
+		t.Errorf("expected embedded fields to round trip, got: %+v, %v", roundTrip, err)
+	}
+
+	actual = new(Document)
+	if err := Parse(map[string]any{"ID": 8, "Title": "Draft"}, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Timestamps != nil {
+		t.Errorf("expected embedded *Timestamps to stay nil, got: %+v", actual.Timestamps)
+	}
+
+	if err := Parse(map[string]any{"Title": "Draft"}, new(Document)); err == nil {
+		t.Errorf("expected required error for the promoted ID field")
+	}
+
+	err = Parse(map[string]any{"ID": 8, "Title": "Draft", "updated_at": "2023-09-12T10:00:00Z"}, new(Document))
+	if err == nil {
+		t.Errorf("expected required error for CreatedAt once *Timestamps is allocated")
+	}
+
+	copied := new(Document)
+	if err := Parse(*expected, copied); err != nil || !reflect.DeepEqual(copied, expected) {
+		t.Errorf("expected identical struct input to be copied, got: %+v, %v", copied, err)
+	}
+}
+
+type revision struct {
+	Revision int
+	author   string
+}
+
+type Page struct {
+	revision
+	Title string
+}
+
+func TestParseEmbeddedUnexportedStruct(t *testing.T) {
+	actual := new(Page)
+	if err := Parse(map[string]any{"Revision": 3, "Title": "Notes", "author": "joe"}, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := &Page{revision{Revision: 3}, "Notes"}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	unparsed, err := Unparse(expected)
+	if err != nil || !reflect.DeepEqual(unparsed, map[string]any{"Revision": int64(3), "Title": "Notes"}) {
+		t.Errorf("expected promoted fields to unparse, got: %#v, %v", unparsed, err)
+	}
+
+	var generic map[string]any
+	if err := Parse(*expected, &generic); err != nil || generic["Revision"] != 3 {
+		t.Errorf("expected promoted fields in a struct input, got: %#v, %v", generic, err)
+	}
+
+	if err := Parse(map[string]any{"Title": "Notes"}, new(Page)); !errors.Is(err, ErrRequiredMissing) {
+		t.Errorf("expected required error for the promoted Revision field, got: %v", err)
+	}
+}
+
+type Base struct {
+	ID   int
+	Name string
+}
+
+type Derived struct {
+	ID int
+	Base
+}
+
+type Audited struct {
+	Note string
+}
+
+type Reviewed struct {
+	Note string
+}
+
+type Change struct {
+	Audited
+	Reviewed
+	Summary string
+}
+
+func TestParseShadowedFields(t *testing.T) {
+	actual := new(Derived)
+	if err := Parse(map[string]any{"ID": 5, "Name": "base"}, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if expected := (&Derived{ID: 5, Base: Base{Name: "base"}}); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected the shallower ID to win.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	tree, err := Unparse(Derived{ID: 1, Base: Base{ID: 2, Name: "base"}})
+	if err != nil || !reflect.DeepEqual(tree, map[string]any{"ID": int64(1), "Name": "base"}) {
+		t.Errorf("expected the shallower ID to be unparsed, got: %#v, %v", tree, err)
+	}
+
+	var generic map[string]any
+	if err := Parse(Derived{ID: 1, Base: Base{ID: 2, Name: "base"}}, &generic); err != nil || generic["ID"] != 1 {
+		t.Errorf("expected the shallower ID in a struct input, got: %#v, %v", generic, err)
+	}
+
+	// Fields of the same name at the same depth are all dropped
+	change := Change{Audited{"a"}, Reviewed{"r"}, "fix"}
+	if err := Parse(map[string]any{"Note": "n", "Summary": "fix"}, &change); err != nil || change != (Change{Audited{"a"}, Reviewed{"r"}, "fix"}) {
+		t.Errorf("expected ambiguous fields to be left as is, got: %+v, %v", change, err)
+	}
+
+	tree, err = Unparse(change)
+	if err != nil || !reflect.DeepEqual(tree, map[string]any{"Summary": "fix"}) {
+		t.Errorf("expected ambiguous fields to be dropped, got: %#v, %v", tree, err)
+	}
+}
+
+type Listener struct {
+	Name string
+	port int
+	host string
+	note string
+}
+
+func (l *Listener) SetPort(port int) error {
+	if port < 1 || port > 65535 {
+		return fmt.Errorf("port %d out of range", port)
+	}
+
+	l.port = port
+	return nil
+}
+
+func (l *Listener) SetHost(host string) {
+	l.host = host
+}
+
+func TestParseSetters(t *testing.T) {
+	p := NewParser(WithUseSetters(true))
+	input := map[string]any{"Name": "api", "port": 8080, "host": "localhost", "note": "ignored"}
+
+	actual := new(Listener)
+	if err := p.Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := Listener{Name: "api", port: 8080, host: "localhost"}
+	if *actual != expected {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", *actual, expected)
+	}
+
+	input["port"] = 70000
+	if err := p.Parse(input, new(Listener)); err == nil || !strings.Contains(err.Error(), "port") {
+		t.Errorf("expected setter error for port, got: %v", err)
+	}
+
+	actual = new(Listener)
+	if err := Parse(input, actual); err != nil || *actual != (Listener{Name: "api"}) {
+		t.Errorf("expected unexported fields to be skipped without UseSetters, got: %+v, %v", *actual, err)
+	}
+}
+
+func TestParseDisallowUnexportedKeys(t *testing.T) {
+	input := map[string]any{"Name": "api", "note": "dropped"}
+
+	actual := new(Listener)
+	if err := Parse(input, actual); err != nil || *actual != (Listener{Name: "api"}) {
+		t.Errorf("expected unexported fields to be skipped by default, got: %+v, %v", *actual, err)
+	}
+
+	p := NewParser(WithDisallowUnexportedKeys(true))
+
+	err := p.Parse(input, new(Listener))
+	if !errors.Is(err, ErrUnknownKey) || !strings.Contains(err.Error(), "unexported field note") {
+		t.Errorf("expected a key matching an unexported field to be rejected, got: %v", err)
+	}
+
+	if err := p.Parse(map[string]any{"Name": "api"}, new(Listener)); err != nil {
+		t.Errorf("expected absent unexported fields to be accepted, got: %v", err)
+	}
+
+	setters := NewParser(WithDisallowUnexportedKeys(true), WithUseSetters(true))
+	if err := setters.Parse(map[string]any{"Name": "api", "port": 80, "host": "localhost"}, new(Listener)); err != nil {
+		t.Errorf("expected unexported fields with a setter to be accepted, got: %v", err)
+	}
+
+	err = NewParser(WithDisallowUnexportedKeys(true), WithCollectErrors(true)).Parse(map[string]any{"note": "x", "host": "y"}, new(Listener))
+	if !errors.Is(err, ErrUnknownKey) || !errors.Is(err, ErrRequiredMissing) || !strings.Contains(err.Error(), "host") {
+		t.Errorf("expected every unexported key to be collected, got: %v", err)
+	}
+}
+
+type Limits struct {
+	Requests int
+	Burst    int
+}
+
+type Gateway struct {
+	Identity
+	Name   string
+	Limits Limits
+	Retry  struct {
+		Attempts *int
+		Backoff  *time.Duration
+	}
+}
+
+func TestParseSkipAbsentStructs(t *testing.T) {
+	input := map[string]any{"Name": "edge"}
+
+	if err := Parse(input, new(Gateway)); !errors.Is(err, ErrRequiredMissing) {
+		t.Errorf("expected absent nested structs to be checked by default, got: %v", err)
+	}
+
+	p := NewParser(WithSkipAbsentStructs(true))
+
+	actual := &Gateway{Limits: Limits{Requests: 10}}
+	if err := p.Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Name != "edge" || actual.Limits != (Limits{Requests: 10}) || actual.ID != 0 {
+		t.Errorf("expected absent structs to be left untouched, got: %+v", actual)
+	}
+
+	err := p.Parse(map[string]any{"Name": "edge", "ID": 1, "Limits": map[string]any{"Burst": 5}}, new(Gateway))
+	if !errors.Is(err, ErrRequiredMissing) || !strings.Contains(err.Error(), "Requests") {
+		t.Errorf("expected present structs to still require their fields, got: %v", err)
+	}
+
+	if err := p.Parse(map[string]any{"Name": "edge", "Limits": nil}, new(Gateway)); err != nil {
+		t.Errorf("expected a null struct to be skipped, got: %v", err)
+	}
+
+	err = p.Parse(map[string]any{"CreatedAt": "2023-09-11T10:00:00Z"}, new(Timestamps))
+	if !errors.Is(err, ErrRequiredMissing) || !strings.Contains(err.Error(), "updated_at") {
+		t.Errorf("expected structs without exported fields such as time.Time to stay required, got: %v", err)
+	}
+}
+
+// WideRecord has many optional nested structs of which sparse input sets few
+type WideRecord struct {
+	A, B, C, D, E, F, G, H, I, J, K, L, M, N, O, P struct {
+		Name    *string
+		Count   *int
+		Enabled *bool
+		Tags    []string `parse:",omitempty"`
+		Nested  struct {
+			Value *float64
+			Note  *string
+		}
+	}
+}
+
+func BenchmarkParseSparseStruct(b *testing.B) {
+	input := map[string]any{
+		"A": map[string]any{"Name": "alpha", "Count": 1.0},
+	}
+
+	for _, skip := range []bool{false, true} {
+		p := NewParser(WithSkipAbsentStructs(skip))
+
+		b.Run(fmt.Sprintf("skip=%t", skip), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if err := p.Parse(input, new(WideRecord)); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+type Crew struct {
+	Name    string
+	Owner   *User
+	Members []User
+	Roles   map[string]User `parse:"roles"`
+}
+
+func TestParseStructToMap(t *testing.T) {
+	createdAt := CreatedAt{time.Date(2023, 9, 11, 10, 0, 0, 0, time.UTC)}
+	user := User{Username: "johndoe", Email: "john@example.com", CreatedAt: createdAt, IsAdmin: true}
+
+	var actual map[string]any
+	if err := Parse(user, &actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := map[string]any{
+		"Username":  Username("johndoe"),
+		"Email":     Email("john@example.com"),
+		"CreatedAt": createdAt,
+		"IsAdmin":   IsAdmin(true),
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %#v\nWant: %#v", actual, expected)
+	}
+
+	roundTrip := new(User)
+	if err := Parse(actual, roundTrip); err != nil || *roundTrip != user {
+		t.Errorf("expected the map to parse back into a User, got: %+v, %v", roundTrip, err)
+	}
+
+	team := Crew{Name: "core", Owner: &user, Members: []User{user}, Roles: map[string]User{"lead": user}}
+
+	var tree map[string]any
+	if err := Parse(team, &tree); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expectedTree := map[string]any{
+		"Name":    "core",
+		"Owner":   expected,
+		"Members": []any{expected},
+		"roles":   map[string]any{"lead": expected},
+	}
+
+	if !reflect.DeepEqual(tree, expectedTree) {
+		t.Errorf("expected nested structs to become maps.\nGot: %#v\nWant: %#v", tree, expectedTree)
+	}
+
+	var labels map[string]string
+	if err := Parse(struct{ Name, Role string }{"joe", "admin"}, &labels); err != nil || labels["Role"] != "admin" {
+		t.Errorf("expected a struct to fill a typed map, got: %v, %v", labels, err)
+	}
+}
+
+type loopNode struct {
+	Name string
+	Next *loopNode
+}
+
+func TestParseStructToMapCycle(t *testing.T) {
+	node := &loopNode{Name: "a"}
+	node.Next = &loopNode{Name: "b", Next: node}
+
+	var actual map[string]any
+	if err := Parse(node, &actual); !errors.Is(err, ErrCycle) {
+		t.Errorf("expected a cycle error, got: %v", err)
+	}
+}
+
+type hiddenOnly struct {
+	count int
+}
+
+func TestParseEmptyStructs(t *testing.T) {
+	var empty struct{}
+	for _, input := range []any{map[string]any{}, map[string]any{"extra": 1.0}, struct{}{}, Identity{ID: 1}} {
+		if err := Parse(input, &empty); err != nil {
+			t.Errorf("expected %T into struct{} to be a no-op, got: %v", input, err)
+		}
+	}
+
+	hidden := hiddenOnly{count: 3}
+	if err := Parse(map[string]any{"count": 5.0}, &hidden); err != nil || hidden.count != 3 {
+		t.Errorf("expected a struct without settable fields to be left as is, got: %+v, %v", hidden, err)
+	}
+
+	for _, input := range []any{"text", 1.0, true, []any{}} {
+		if err := Parse(input, &empty); !errors.Is(err, ErrTypeMismatch) {
+			t.Errorf("expected %T into struct{} to be a type mismatch, got: %v", input, err)
+		}
+
+		if err := Parse(input, &hidden); !errors.Is(err, ErrTypeMismatch) {
+			t.Errorf("expected %T into a struct without settable fields to be a type mismatch, got: %v", input, err)
+		}
+	}
+
+	if err := Parse(nil, &empty); !errors.Is(err, ErrRequiredMissing) {
+		t.Errorf("expected a nil struct{} to be required like other values, got: %v", err)
+	}
+
+	if err := NewParser(WithDisallowUnknownKeys(true)).Parse(map[string]any{"extra": 1.0}, &empty); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected keys into struct{} to be unknown, got: %v", err)
+	}
+
+	var set map[string]struct{}
+	if err := Parse(map[string]any{"a": map[string]any{}, "b": map[string]any{}}, &set); err != nil || len(set) != 2 {
+		t.Errorf("expected a set of empty structs, got: %v, %v", set, err)
+	}
+
+	tree, err := Unparse(struct{}{})
+	if err != nil || !reflect.DeepEqual(tree, map[string]any{}) {
+		t.Errorf("expected struct{} to unparse into an empty map, got: %#v, %v", tree, err)
+	}
+}
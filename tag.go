@@ -0,0 +1,43 @@
+package kaeru
+
+import (
+	"strings"
+)
+
+// tagOptions are the comma separated options following the name in a parse
+// tag, either flags such as remain or key=value pairs such as alias=mail
+type tagOptions []string
+
+// parseTag splits a parse tag into the field name and its options, following
+// the encoding/json convention. A tag of "-" skips the field, callers check it
+// before parsing since "-," names a field "-".
+func parseTag(tag string) (string, tagOptions) {
+	name, rest, found := strings.Cut(tag, ",")
+	if !found {
+		return name, nil
+	}
+
+	return name, strings.Split(rest, ",")
+}
+
+// Has reports whether the flag is set
+func (o tagOptions) Has(flag string) bool {
+	for _, opt := range o {
+		if opt == flag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Get returns the value of the first key=value option with the given key
+func (o tagOptions) Get(key string) (string, bool) {
+	for _, opt := range o {
+		if value, ok := strings.CutPrefix(opt, key+"="); ok {
+			return value, true
+		}
+	}
+
+	return "", false
+}
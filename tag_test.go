@@ -0,0 +1,102 @@
+package kaeru
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTag(t *testing.T) {
+	name, opts := parseTag("email,alias=mail,enum=a|b,remain")
+	if name != "email" {
+		t.Errorf("expected name email, got: %q", name)
+	}
+
+	if !opts.Has("remain") || opts.Has("alias") {
+		t.Errorf("unexpected flags in %v", opts)
+	}
+
+	if alias, ok := opts.Get("alias"); !ok || alias != "mail" {
+		t.Errorf("expected alias mail, got: %q, %v", alias, ok)
+	}
+
+	if _, ok := opts.Get("default"); ok {
+		t.Errorf("expected missing default option")
+	}
+
+	name, opts = parseTag(",remain")
+	if name != "" || !opts.Has("remain") {
+		t.Errorf("unexpected result for unnamed tag: %q, %v", name, opts)
+	}
+
+	name, opts = parseTag("-,")
+	if name != "-" || len(opts) != 1 {
+		t.Errorf("expected field named -, got: %q, %v", name, opts)
+	}
+}
+
+type Secret struct {
+	Name     string
+	Password string `parse:"-"`
+	Dash     string `parse:"-,"`
+}
+
+func TestParseSkippedField(t *testing.T) {
+	actual := Secret{Password: "hunter2"}
+	err := Parse(map[string]any{"Name": "db", "Password": "leaked", "-": "dash"}, &actual)
+	if err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := Secret{Name: "db", Password: "hunter2", Dash: "dash"}
+	if actual != expected {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	unparsed, err := Unparse(expected)
+	if err != nil {
+		t.Fatalf("Unparse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(unparsed, map[string]any{"Name": "db", "-": "dash"}) {
+		t.Errorf("expected skipped field to be left out of Unparse, got: %v", unparsed)
+	}
+}
+
+type Person struct {
+	Born     time.Time    `parse:"born,layout=2006-01-02"`
+	Visits   []*time.Time `parse:"visits,layout=2006-01-02 15:04"`
+	Updated  time.Time    `parse:"updated"`
+	Nickname string       `parse:"nickname,layout=2006"`
+}
+
+func TestParseTimeLayoutTag(t *testing.T) {
+	input := map[string]any{
+		"born":     "1990-05-17",
+		"visits":   []any{"2023-09-11 10:00"},
+		"updated":  "2023-09-11T10:00:00Z",
+		"nickname": "2006",
+	}
+
+	actual := new(Person)
+	if err := Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	visit := time.Date(2023, 9, 11, 10, 0, 0, 0, time.UTC)
+	expected := &Person{
+		Born:     time.Date(1990, 5, 17, 0, 0, 0, 0, time.UTC),
+		Visits:   []*time.Time{&visit},
+		Updated:  visit,
+		Nickname: "2006",
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	input["born"] = "1990-05-17T00:00:00Z"
+	if err := Parse(input, new(Person)); err == nil {
+		t.Errorf("expected the field layout to replace the default layout")
+	}
+}
@@ -0,0 +1,42 @@
+package kaeru
+
+import (
+	"reflect"
+)
+
+// TraceEvent describes a step of parsing reported to Options.Trace
+type TraceEvent struct {
+	// Path is the location of the value, such as Comments[0].Body, and is
+	// empty for the value passed to Parse
+	Path string
+
+	// Input is the type of the input value, nil when it is absent or null
+	Input reflect.Type
+
+	// Output is the type the value is parsed into
+	Output reflect.Type
+
+	// Parser names the custom parser called for the value, such as
+	// ParseString, SetDefault or hook for a stdlib hook. It is empty for the
+	// event reported when parsing of the value starts.
+	Parser string
+}
+
+// trace reports an event to Options.Trace when one is set
+func (s *state) trace(parser string, inVal reflect.Value, outVal reflect.Value) {
+	if s.opts.Trace == nil {
+		return
+	}
+
+	event := TraceEvent{
+		Path:   formatPath(s.path),
+		Output: outVal.Type(),
+		Parser: parser,
+	}
+
+	if inVal.IsValid() {
+		event.Input = inVal.Type()
+	}
+
+	s.opts.Trace(event)
+}
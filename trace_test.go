@@ -0,0 +1,41 @@
+package kaeru
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestParseTrace(t *testing.T) {
+	var events []TraceEvent
+	parser := NewParser(WithTrace(func(event TraceEvent) {
+		events = append(events, event)
+	}))
+
+	input := map[string]any{
+		"Title":    "My First Post",
+		"Body":     "This is the content of my first post.",
+		"Labels":   []any{"new"},
+		"Upvotes":  42.0,
+		"Poster":   map[string]any{"Username": "johndoe", "Email": "john@example.com", "CreatedAt": "2023-09-11T10:00:00Z", "IsAdmin": true},
+		"Comments": []any{},
+	}
+
+	if err := parser.Parse(input, new(Post)); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := []TraceEvent{
+		{Path: "", Input: reflect.TypeFor[map[string]any](), Output: reflect.TypeFor[Post]()},
+		{Path: "Metadata", Input: nil, Output: reflect.TypeFor[*Metadata]()},
+		{Path: "Labels[0]", Input: reflect.TypeFor[string](), Output: reflect.TypeFor[Label](), Parser: "ParseString"},
+		{Path: "Upvotes", Input: reflect.TypeFor[float64](), Output: reflect.TypeFor[Upvotes](), Parser: "ParseFloat64"},
+		{Path: "Poster.IsAdmin", Input: reflect.TypeFor[bool](), Output: reflect.TypeFor[IsAdmin]()},
+	}
+
+	for _, event := range expected {
+		if !slices.Contains(events, event) {
+			t.Errorf("expected event %+v, got: %+v", event, events)
+		}
+	}
+}
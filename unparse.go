@@ -0,0 +1,308 @@
+package kaeru
+
+import (
+	"database/sql/driver"
+	"encoding"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// Format lets a type choose its own representation when it is unparsed
+type Format interface {
+	Format() (any, error)
+}
+
+// Unparse is the inverse of Parse. It walks structs, maps, slices and
+// primitives and builds a tree of map[string]any, []any and builtin values
+// suitable for json.Marshal. Struct keys honor the parse tag. Types
+// implementing Format, driver.Valuer, encoding.TextMarshaler or fmt.Stringer
+// are unparsed through the first of those methods they have, and times are
+// formatted with Options.TimeLayout. Values that contain themselves fail with
+// ErrCycle.
+func Unparse(input any) (any, error) {
+	return defaultParser.Unparse(input)
+}
+
+func (p *Parser) Unparse(input any) (any, error) {
+	s := p.newState()
+	defer p.release(s)
+
+	return s.unparseValue(reflect.ValueOf(input))
+}
+
+func (s *state) unparseValue(inVal reflect.Value) (any, error) {
+	if err := s.enter(); err != nil {
+		return nil, err
+	}
+	defer s.leave()
+
+	for inVal.Kind() == reflect.Pointer || inVal.Kind() == reflect.Interface {
+		if inVal.IsNil() {
+			return nil, nil
+		}
+
+		if formatter, ok := inVal.Interface().(Format); ok {
+			return formatter.Format()
+		}
+
+		key, err := s.push(inVal)
+		if err != nil {
+			return nil, err
+		}
+		defer s.pop(key)
+
+		inVal = inVal.Elem()
+	}
+
+	if !inVal.IsValid() {
+		return nil, nil
+	}
+
+	if formatter, ok := as[Format](inVal); ok {
+		return formatter.Format()
+	}
+
+	if inVal.Type() == timeType {
+		return inVal.Interface().(time.Time).Format(s.timeLayout()), nil
+	}
+
+	// Types with their own serialized or string form are unparsed through
+	// it rather than by reflecting over their fields
+	if valuer, ok := as[driver.Valuer](inVal); ok {
+		return valuer.Value()
+	}
+
+	if marshaler, ok := as[encoding.TextMarshaler](inVal); ok {
+		text, err := marshaler.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+
+		return string(text), nil
+	}
+
+	if stringer, ok := as[fmt.Stringer](inVal); ok {
+		return stringer.String(), nil
+	}
+
+	// Maps and slices holding themselves are cycles too, pointers were
+	// pushed above
+	key, err := s.push(inVal)
+	if err != nil {
+		return nil, err
+	}
+	defer s.pop(key)
+
+	switch inVal.Kind() {
+	case reflect.Bool:
+		return inVal.Bool(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return inVal.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return inVal.Uint(), nil
+	case reflect.Float32, reflect.Float64:
+		return inVal.Float(), nil
+	case reflect.String:
+		return inVal.String(), nil
+	case reflect.Struct:
+		return s.unparseStruct(inVal)
+	case reflect.Map:
+		return s.unparseMap(inVal)
+	case reflect.Slice:
+		if inVal.IsNil() {
+			return nil, nil
+		}
+		fallthrough
+	case reflect.Array:
+		return s.unparseSlice(inVal)
+	default:
+		return nil, fmt.Errorf("%w: cannot unparse kind %s", ErrUnsupportedKind, inVal.Kind())
+	}
+}
+
+// as returns inVal, or a pointer to it when addressable, as a T
+func as[T any](inVal reflect.Value) (T, bool) {
+	if v, ok := inVal.Interface().(T); ok {
+		return v, true
+	}
+
+	if inVal.CanAddr() {
+		if v, ok := inVal.Addr().Interface().(T); ok {
+			return v, true
+		}
+	}
+
+	var zero T
+	return zero, false
+}
+
+func (s *state) unparseStruct(inVal reflect.Value) (any, error) {
+	out := make(map[string]any, inVal.NumField())
+	if err := s.unparseFields(inVal, out, shadowedFields(inVal.Type()), 0); err != nil {
+		return nil, err
+	}
+
+	return out, nil
+}
+
+// unparseFields adds the fields of inVal to out, flattening untagged embedded
+// structs the same way parsing promotes them. depth is the number of embedded
+// structs inVal is nested in, to skip the hidden fields.
+func (s *state) unparseFields(inVal reflect.Value, out map[string]any, hidden shadowed, depth int) error {
+	inType := inVal.Type()
+
+	for i := 0; i < inVal.NumField(); i++ {
+		fieldType := inType.Field(i)
+		fieldName := fieldType.Name
+
+		tag := fieldType.Tag.Get("parse")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := parseTag(tag)
+		if name != "" {
+			fieldName = name
+		}
+
+		// Skip unexported fields, apart from embedded structs whose exported
+		// fields are promoted
+		if !fieldType.IsExported() && (name != "" || !isEmbeddedStruct(fieldType)) {
+			continue
+		}
+
+		if opts.Has("omitempty") && isEmptyValue(inVal.Field(i)) {
+			continue
+		}
+
+		if name == "" && isEmbeddedStruct(fieldType) {
+			field := inVal.Field(i)
+			if field.Kind() == reflect.Pointer {
+				if field.IsNil() {
+					continue
+				}
+
+				field = field.Elem()
+			}
+
+			if err := s.unparseFields(field, out, hidden, depth+1); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if hidden.hides(fieldName, depth) {
+			continue
+		}
+
+		var (
+			value any
+			err   error
+		)
+
+		// An inline map is flattened into the struct, never replacing the
+		// value of a field with the same key
+		if opts.Has("inline") && inVal.Field(i).Kind() == reflect.Map {
+			if err := s.unparseInline(inVal.Field(i), out); err != nil {
+				return fmt.Errorf("error unparsing field %s: %w", fieldType.Name, err)
+			}
+
+			continue
+		}
+
+		if keyName, valueName, ok := entryNames(opts); ok && inVal.Field(i).Kind() == reflect.Map {
+			value, err = s.unparseEntries(inVal.Field(i), keyName, valueName)
+		} else {
+			value, err = s.unparseValue(inVal.Field(i))
+		}
+
+		if err != nil {
+			return fmt.Errorf("error unparsing field %s: %w", fieldName, err)
+		}
+
+		out[fieldName] = value
+	}
+
+	return nil
+}
+
+// unparseInline adds the entries of the map inVal to out, keeping the keys
+// out already has
+func (s *state) unparseInline(inVal reflect.Value, out map[string]any) error {
+	inline, err := s.unparseMap(inVal)
+	if err != nil || inline == nil {
+		return err
+	}
+
+	for key, value := range inline.(map[string]any) {
+		if _, ok := out[key]; !ok {
+			out[key] = value
+		}
+	}
+
+	return nil
+}
+
+// isEmptyValue reports whether v is empty by the encoding/json definition
+// used for omitempty: false, zero, a nil pointer or interface, or an empty
+// string, map, slice or array
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String, reflect.Map, reflect.Slice, reflect.Array:
+		return v.Len() == 0
+	case reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Float32, reflect.Float64,
+		reflect.Pointer, reflect.Interface:
+		return v.IsZero()
+	default:
+		return false
+	}
+}
+
+func (s *state) unparseMap(inVal reflect.Value) (any, error) {
+	if inVal.IsNil() {
+		return nil, nil
+	}
+
+	out := make(map[string]any, inVal.Len())
+
+	iter := inVal.MapRange()
+	for iter.Next() {
+		key := iter.Key()
+
+		var outKey string
+		if key.Kind() == reflect.String {
+			outKey = key.String()
+		} else {
+			outKey = fmt.Sprint(key.Interface())
+		}
+
+		value, err := s.unparseValue(iter.Value())
+		if err != nil {
+			return nil, fmt.Errorf("error unparsing map value %s: %w", outKey, err)
+		}
+
+		out[outKey] = value
+	}
+
+	return out, nil
+}
+
+func (s *state) unparseSlice(inVal reflect.Value) (any, error) {
+	out := make([]any, inVal.Len())
+
+	for i := range out {
+		value, err := s.unparseValue(inVal.Index(i))
+		if err != nil {
+			return nil, fmt.Errorf("error unparsing element at index %d: %w", i, err)
+		}
+
+		out[i] = value
+	}
+
+	return out, nil
+}
@@ -0,0 +1,269 @@
+package kaeru
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type Coordinates struct {
+	Lat float64
+	Lng float64
+}
+
+func (c Coordinates) Format() (any, error) {
+	return []any{c.Lat, c.Lng}, nil
+}
+
+type Place struct {
+	Name        string `parse:"name"`
+	Coordinates Coordinates
+	Aliases     []string
+	hidden      string
+}
+
+func TestUnparse(t *testing.T) {
+	input := &Place{
+		Name:        "Stockholm",
+		Coordinates: Coordinates{59.3, 18.1},
+		Aliases:     []string{"Sthlm"},
+		hidden:      "secret",
+	}
+
+	expected := map[string]any{
+		"name":        "Stockholm",
+		"Coordinates": []any{59.3, 18.1},
+		"Aliases":     []any{"Sthlm"},
+	}
+
+	actual, err := Unparse(input)
+	if err != nil {
+		t.Fatalf("Unparse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unparse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+}
+
+func TestUnparseRoundTrip(t *testing.T) {
+	createdAt := CreatedAt{time.Date(2023, 9, 11, 10, 0, 0, 0, time.UTC)}
+	expected := &Post{
+		Title:    "My First Post",
+		Body:     "This is the content of my first post.",
+		Metadata: &Metadata{"category": "tech"},
+		Labels:   []Label{"new"},
+		Upvotes:  42,
+		Poster: User{
+			Username:  "johndoe",
+			Email:     "john@example.com",
+			CreatedAt: createdAt,
+		},
+		Comments: []Comment{
+			{
+				Body:     "Great post! Looking forward to more.",
+				Metadata: Metadata{},
+				Upvotes:  5,
+				Commenter: User{
+					Username:  "janedoe",
+					Email:     "jane@example.com",
+					CreatedAt: createdAt,
+				},
+			},
+		},
+		AdminNote: ptr("Approved for front page"),
+	}
+
+	tree, err := Unparse(expected)
+	if err != nil {
+		t.Fatalf("Unparse returned an error: %v", err)
+	}
+
+	actual := new(Post)
+	if err := Parse(tree, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Round trip result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+}
+
+func TestUnparseStringerAndValuer(t *testing.T) {
+	createdAt := CreatedAt{time.Date(2023, 9, 11, 10, 0, 0, 0, time.UTC)}
+
+	actual, err := Unparse(map[string]any{
+		"created": createdAt,
+		"name":    sql.NullString{String: "john", Valid: true},
+		"missing": sql.NullInt64{},
+		"at":      createdAt.Time,
+	})
+	if err != nil {
+		t.Fatalf("Unparse returned an error: %v", err)
+	}
+
+	expected := map[string]any{
+		"created": "2023-09-11T10:00:00Z",
+		"name":    "john",
+		"missing": nil,
+		"at":      "2023-09-11T10:00:00Z",
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unparse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+}
+
+type Profile struct {
+	Name     string            `parse:"name"`
+	Nickname string            `parse:"nickname,omitempty"`
+	Age      int               `parse:"age,omitempty"`
+	Verified bool              `parse:"verified,omitempty"`
+	Website  *string           `parse:"website,omitempty"`
+	Links    []string          `parse:"links,omitempty"`
+	Extra    map[string]string `parse:"extra,omitempty"`
+	Rating   float64           `parse:"rating,omitempty"`
+	Email    Email             `parse:"email,omitempty"`
+}
+
+func TestUnparseOmitEmpty(t *testing.T) {
+	input := &Profile{
+		Name:  "Joe",
+		Age:   42,
+		Links: []string{},
+		Extra: map[string]string{"team": "erlang"},
+	}
+
+	expected := map[string]any{
+		"name":  "Joe",
+		"age":   int64(42),
+		"extra": map[string]any{"team": "erlang"},
+	}
+
+	actual, err := Unparse(input)
+	if err != nil {
+		t.Fatalf("Unparse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Unparse result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+
+	parsed := new(Profile)
+	if err := Parse(actual, parsed); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	input.Links = nil
+	if !reflect.DeepEqual(parsed, input) {
+		t.Errorf("Round trip not as expected.\nGot: %+v\nWant: %+v", parsed, input)
+	}
+
+	if err := Parse(map[string]any{}, new(Profile)); !errors.Is(err, ErrRequiredMissing) {
+		t.Errorf("expected name without omitempty to stay required, got: %v", err)
+	}
+}
+
+type Version struct {
+	Major int
+	Minor int
+}
+
+func (v Version) MarshalText() ([]byte, error) {
+	return fmt.Appendf(nil, "v%d.%d", v.Major, v.Minor), nil
+}
+
+func (v *Version) ParseString(s string) error {
+	if _, err := fmt.Sscanf(s, "v%d.%d", &v.Major, &v.Minor); err != nil {
+		return fmt.Errorf("invalid version %q: %w", s, err)
+	}
+
+	return nil
+}
+
+type Release struct {
+	Version   Version   `parse:"version"`
+	Previous  *Version  `parse:"previous"`
+	Published time.Time `parse:"published"`
+}
+
+func TestUnparseTextMarshaler(t *testing.T) {
+	expected := &Release{
+		Version:   Version{1, 2},
+		Previous:  &Version{1, 1},
+		Published: time.Date(2023, 9, 11, 10, 0, 0, 0, time.UTC),
+	}
+
+	tree, err := Unparse(expected)
+	if err != nil {
+		t.Fatalf("Unparse returned an error: %v", err)
+	}
+
+	expectedTree := map[string]any{
+		"version":   "v1.2",
+		"previous":  "v1.1",
+		"published": "2023-09-11T10:00:00Z",
+	}
+
+	if !reflect.DeepEqual(tree, expectedTree) {
+		t.Errorf("Unparse result not as expected.\nGot: %+v\nWant: %+v", tree, expectedTree)
+	}
+
+	actual := new(Release)
+	if err := Parse(tree, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Round trip result not as expected.\nGot: %+v\nWant: %+v", actual, expected)
+	}
+}
+
+func TestUnparseTimeLayout(t *testing.T) {
+	p := NewParser(WithTimeLayout(time.DateOnly))
+	published := time.Date(2023, 9, 11, 0, 0, 0, 0, time.UTC)
+
+	tree, err := p.Unparse(Release{Published: published})
+	if err != nil {
+		t.Fatalf("Unparse returned an error: %v", err)
+	}
+
+	if got := tree.(map[string]any)["published"]; got != "2023-09-11" {
+		t.Errorf("Expected published to be formatted as 2023-09-11, got %v", got)
+	}
+
+	actual := new(Release)
+	if err := p.Parse(tree, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if !actual.Published.Equal(published) {
+		t.Errorf("Expected published %v, got %v", published, actual.Published)
+	}
+}
+
+func TestUnparseCycle(t *testing.T) {
+	shared := &treeNode{Value: 1}
+	tree, err := Unparse([]*treeNode{shared, shared})
+	if err != nil || len(tree.([]any)) != 2 {
+		t.Fatalf("expected a value reached twice without a cycle to unparse, got: %v, %v", tree, err)
+	}
+
+	node := &treeNode{Value: 1}
+	node.Child = node
+
+	cyclic := map[string]any{"Value": 1}
+	cyclic["Child"] = cyclic
+
+	list := []any{nil}
+	list[0] = list
+
+	for _, input := range []any{node, cyclic, list} {
+		if _, err := Unparse(input); !errors.Is(err, ErrCycle) || len(err.Error()) > 1024 {
+			t.Errorf("expected a short cycle error for %T, got: %.200v", input, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package kaeru
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+)
+
+// variants are the concrete types registered for an interface, picked by the
+// value of a discriminator field in the input map
+type variants struct {
+	field string
+	types map[string]reflect.Type
+}
+
+// WithVariant registers V as the concrete type parsed into an interface I
+// when the field of the input map equals value, such as
+// WithVariant[Event, Click]("type", "click"). Either V or *V must implement
+// I and the first field registered for an interface is used for all of its
+// variants. The discriminator is parsed into the variant like any other key.
+func WithVariant[I any, V any](field string, value string) Option {
+	iface := reflect.TypeFor[I]()
+	concrete := reflect.TypeFor[V]()
+
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("kaeru: WithVariant: %s is not an interface", iface))
+	}
+
+	if !concrete.Implements(iface) {
+		concrete = reflect.PointerTo(concrete)
+		if !concrete.Implements(iface) {
+			panic(fmt.Sprintf("kaeru: WithVariant: neither %s nor %s implements %s", concrete.Elem(), concrete, iface))
+		}
+	}
+
+	return func(o *Options) {
+		registered := make(map[reflect.Type]variants, len(o.variants)+1)
+		maps.Copy(registered, o.variants)
+
+		set, ok := registered[iface]
+		if !ok {
+			set.field = field
+		}
+
+		types := make(map[string]reflect.Type, len(set.types)+1)
+		maps.Copy(types, set.types)
+		types[value] = concrete
+
+		set.types = types
+		registered[iface] = set
+		o.variants = registered
+	}
+}
+
+// parseVariant allocates the concrete type registered for the discriminator
+// of the input map and parses the input into it
+func (s *state) parseVariant(inVal reflect.Value, outVal reflect.Value, set variants) error {
+	discriminator := s.lookupKey(inVal, set.field)
+	if discriminator.Kind() == reflect.Interface {
+		discriminator = discriminator.Elem()
+	}
+
+	if !discriminator.IsValid() {
+		return fmt.Errorf("%w: %s to pick a variant of %s", ErrRequiredMissing, set.field, outVal.Type())
+	}
+
+	if discriminator.Kind() != reflect.String {
+		return fmt.Errorf("%w: %s of %s must be a string, got %s", ErrTypeMismatch, set.field, outVal.Type(), discriminator.Type())
+	}
+
+	concrete, ok := set.types[discriminator.String()]
+	if !ok {
+		return fmt.Errorf("%w: %s %q of %s", ErrUnknownVariant, set.field, discriminator.String(), outVal.Type())
+	}
+
+	// Pointer variants are allocated and parsed into through the pointer
+	out := reflect.New(concrete).Elem()
+	if err := s.parseValue(inVal, out); err != nil {
+		return err
+	}
+
+	outVal.Set(out)
+	return nil
+}
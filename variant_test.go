@@ -0,0 +1,93 @@
+package kaeru
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type Event interface {
+	EventName() string
+}
+
+type Click struct {
+	Type string `parse:"type"`
+	X    int    `parse:"x"`
+	Y    int    `parse:"y"`
+}
+
+func (c Click) EventName() string { return "click" }
+
+type KeyPress struct {
+	Type string `parse:"type"`
+	Key  rune   `parse:"key"`
+}
+
+func (k *KeyPress) EventName() string { return "keypress" }
+
+type Session struct {
+	Events []Event `parse:"events"`
+	Last   Event   `parse:"last"`
+}
+
+func TestParseVariants(t *testing.T) {
+	parser := NewParser(
+		WithVariant[Event, Click]("type", "click"),
+		WithVariant[Event, KeyPress]("type", "keypress"),
+	)
+
+	input := map[string]any{
+		"events": []any{
+			map[string]any{"type": "click", "x": 10.0, "y": 20.0},
+			map[string]any{"type": "keypress", "key": 97.0},
+		},
+		"last": map[string]any{"type": "click", "x": 1.0, "y": 2.0},
+	}
+
+	actual := new(Session)
+	if err := parser.Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := &Session{
+		Events: []Event{
+			Click{Type: "click", X: 10, Y: 20},
+			&KeyPress{Type: "keypress", Key: 'a'},
+		},
+		Last: Click{Type: "click", X: 1, Y: 2},
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nExpected: %+v", actual, expected)
+	}
+
+	cases := []struct {
+		last     map[string]any
+		expected error
+	}{
+		{map[string]any{"x": 1.0}, ErrRequiredMissing},
+		{map[string]any{"type": "scroll"}, ErrUnknownVariant},
+		{map[string]any{"type": 1.0}, ErrTypeMismatch},
+	}
+
+	for _, c := range cases {
+		input["last"] = c.last
+		if err := parser.Parse(input, new(Session)); !errors.Is(err, c.expected) {
+			t.Errorf("expected %v for %v, got: %v", c.expected, c.last, err)
+		}
+	}
+
+	if err := Parse(input, new(Session)); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected maps to be rejected without variants, got: %v", err)
+	}
+}
+
+func TestWithVariantPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("expected WithVariant to panic for a type not implementing the interface")
+		}
+	}()
+
+	WithVariant[Event, Square]("type", "square")
+}
@@ -0,0 +1,105 @@
+package kaeru
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"unicode/utf8"
+)
+
+// parseNumericString parses a string input into a numeric output under
+// WeaklyTypedInput. Integers use Options.NumberBase, floats accept any
+// notation strconv.ParseFloat does and int32 outputs, which include rune,
+// also accept a single character. Reports false when outVal is not numeric.
+func (s *state) parseNumericString(str string, outVal reflect.Value) (bool, error) {
+	var (
+		parsed any
+		err    error
+	)
+
+	switch outVal.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err = strconv.ParseInt(str, s.opts.NumberBase, outVal.Type().Bits())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		parsed, err = strconv.ParseUint(str, s.opts.NumberBase, outVal.Type().Bits())
+	case reflect.Float32, reflect.Float64:
+		parsed, err = strconv.ParseFloat(str, outVal.Type().Bits())
+	default:
+		return false, nil
+	}
+
+	// A rune is an int32, so a single character that is not a digit is
+	// taken as its code point
+	if errors.Is(err, strconv.ErrSyntax) && outVal.Kind() == reflect.Int32 {
+		if r, size := utf8.DecodeRuneInString(str); size > 0 && size == len(str) && r != utf8.RuneError {
+			parsed, err = int64(r), nil
+		} else {
+			err = fmt.Errorf("%q is not a number or a single character: %w", str, err)
+		}
+	}
+
+	if errors.Is(err, strconv.ErrRange) {
+		return true, fmt.Errorf("%w: %w", ErrOverflow, err)
+	} else if err != nil {
+		return true, fmt.Errorf("%w: %w", ErrTypeMismatch, err)
+	}
+
+	return true, s.parsePrimitive(reflect.ValueOf(parsed), outVal)
+}
+
+// parseBoolString parses a string input such as "true" or "0" into a bool
+// output under WeaklyTypedInput. Reports false when outVal is not a bool.
+func parseBoolString(str string, outVal reflect.Value) (bool, error) {
+	if outVal.Kind() != reflect.Bool {
+		return false, nil
+	}
+
+	b, err := strconv.ParseBool(str)
+	if err != nil {
+		return true, fmt.Errorf("%w: %w", ErrTypeMismatch, err)
+	}
+
+	outVal.SetBool(b)
+	return true, nil
+}
+
+// parseWeakScalar converts a number or bool input under WeaklyTypedInput.
+// Both are formatted into strings, bools become 1 or 0 and numbers are true
+// unless zero. Reports false when no conversion applies.
+func (s *state) parseWeakScalar(inVal reflect.Value, outVal reflect.Value) (bool, error) {
+	switch {
+	case outVal.Kind() == reflect.String:
+		outVal.SetString(fmt.Sprint(inVal.Interface()))
+		return true, nil
+	case isBool(inVal.Kind()) && isNumber(outVal.Kind()):
+		n := 0
+		if inVal.Bool() {
+			n = 1
+		}
+
+		return true, s.parsePrimitive(reflect.ValueOf(n), outVal)
+	case isNumber(inVal.Kind()) && isBool(outVal.Kind()):
+		outVal.SetBool(!inVal.IsZero())
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// parseByteString passes a []byte input of valid UTF-8 to the ParseString
+// method of outVal as text under WeaklyTypedInput. Reports false when either
+// does not apply.
+func (s *state) parseByteString(inVal reflect.Value, outVal reflect.Value) (bool, error) {
+	if inVal.Kind() != reflect.Slice || inVal.Type().Elem().Kind() != reflect.Uint8 {
+		return false, nil
+	}
+
+	parser, ok := outVal.Addr().Interface().(ParseString)
+	if !ok || !utf8.Valid(inVal.Bytes()) {
+		return false, nil
+	}
+
+	s.trace("ParseString", inVal, outVal)
+	return true, parser.ParseString(string(inVal.Bytes()))
+}
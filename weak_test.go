@@ -0,0 +1,277 @@
+package kaeru
+
+import (
+	"errors"
+	"maps"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNumericStrings(t *testing.T) {
+	weak := NewParser(WithWeaklyTypedInput(true))
+	literal := NewParser(WithWeaklyTypedInput(true), WithNumberBase(0))
+
+	var i int
+	if err := weak.Parse("42", &i); err != nil || i != 42 {
+		t.Errorf("expected 42, got: %d, %v", i, err)
+	}
+
+	if err := weak.Parse("0x1F", &i); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected hex to be rejected in base 10, got: %v", err)
+	}
+
+	cases := []struct {
+		input    string
+		expected int64
+	}{
+		{"1_000", 1000},
+		{"0x1F", 31},
+		{"0b101", 5},
+		{"0o17", 15},
+		{"-42", -42},
+	}
+
+	for _, c := range cases {
+		var actual int64
+		if err := literal.Parse(c.input, &actual); err != nil || actual != c.expected {
+			t.Errorf("expected %s to parse as %d, got: %d, %v", c.input, c.expected, actual, err)
+		}
+	}
+
+	var f float64
+	if err := weak.Parse("1e3", &f); err != nil || f != 1000 {
+		t.Errorf("expected 1e3 to parse as 1000, got: %v, %v", f, err)
+	}
+
+	var u uint8
+	if err := weak.Parse("300", &u); !errors.Is(err, ErrOverflow) {
+		t.Errorf("expected overflow error for 300 into uint8, got: %v", err)
+	}
+
+	var upvotes Upvotes
+	if err := weak.Parse("12", &upvotes); err != nil || upvotes != 12 {
+		t.Errorf("expected 12 upvotes, got: %d, %v", upvotes, err)
+	}
+
+	if err := Parse("42", &i); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected numeric string to be rejected without weak typing, got: %v", err)
+	}
+}
+
+func TestParseWeaklyTypedInput(t *testing.T) {
+	weak := NewParser(WithWeaklyTypedInput(true))
+
+	type Settings struct {
+		Enabled bool
+		Debug   bool
+		Retries int
+		Port    string
+		Verbose string
+		Ratio   float64
+		Owner   Username
+		Labels  []Label
+		Scores  []int
+		Name    string
+		Limit   int
+	}
+
+	input := map[string]any{
+		"Enabled": "true",
+		"Debug":   1,
+		"Retries": true,
+		"Port":    8080,
+		"Verbose": false,
+		"Ratio":   "0.5",
+		"Owner":   12345,
+		"Labels":  "new",
+		"Scores":  7,
+		"Name":    []any{"kaeru"},
+		"Limit":   []any{"10"},
+	}
+
+	actual := new(Settings)
+	if err := weak.Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := &Settings{
+		Enabled: true,
+		Debug:   true,
+		Retries: 1,
+		Port:    "8080",
+		Verbose: "false",
+		Ratio:   0.5,
+		Owner:   "12345",
+		Labels:  []Label{"new"},
+		Scores:  []int{7},
+		Name:    "kaeru",
+		Limit:   10,
+	}
+
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nExpected: %+v", actual, expected)
+	}
+
+	var b bool
+	if err := weak.Parse("maybe", &b); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected a non bool string to be rejected, got: %v", err)
+	}
+
+	var name string
+	if err := weak.Parse([]any{"a", "b"}, &name); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected a slice of two to be rejected, got: %v", err)
+	}
+
+	var labels []Label
+	if err := weak.Parse("", &labels); err == nil {
+		t.Errorf("expected the wrapped element to be validated by ParseString")
+	}
+
+	valid, err := Unparse(expected)
+	if err != nil {
+		t.Fatalf("Unparse returned an error: %v", err)
+	}
+
+	if err := Parse(valid, new(Settings)); err != nil {
+		t.Fatalf("Parse returned an error for the strict input: %v", err)
+	}
+
+	for _, key := range []string{"Enabled", "Debug", "Retries", "Verbose", "Labels", "Name"} {
+		strict := maps.Clone(valid.(map[string]any))
+		strict[key] = input[key]
+
+		if err := Parse(strict, new(Settings)); err == nil {
+			t.Errorf("expected %s of %v to be rejected without weak typing", key, input[key])
+		}
+	}
+}
+
+func TestParseSingleValueLists(t *testing.T) {
+	weak := NewParser(WithWeaklyTypedInput(true))
+
+	type Config struct {
+		Labels  *[]Label
+		Tags    Tags
+		Ports   [2]int
+		Owners  []User
+		Timeout string
+		Replica int
+	}
+
+	input := map[string]any{
+		"Labels": "new",
+		"Tags":   "golang",
+		"Ports":  8080,
+		"Owners": []any{map[string]any{
+			"Username":  "johndoe",
+			"Email":     "john@example.com",
+			"CreatedAt": "2023-09-11T10:00:00Z",
+			"IsAdmin":   true,
+		}},
+		"Timeout": [1]string{"5s"},
+		"Replica": [1]any{3.0},
+	}
+
+	actual := new(Config)
+	if err := weak.Parse(input, actual); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if actual.Labels == nil || !reflect.DeepEqual(*actual.Labels, []Label{"new"}) {
+		t.Errorf("expected a single label, got: %v", actual.Labels)
+	}
+
+	if !reflect.DeepEqual(actual.Tags, Tags{"golang"}) {
+		t.Errorf("expected ParseStringSlice to receive the single tag, got: %v", actual.Tags)
+	}
+
+	if actual.Ports != [2]int{8080, 0} {
+		t.Errorf("expected the port to fill the first array element, got: %v", actual.Ports)
+	}
+
+	if len(actual.Owners) != 1 || actual.Owners[0].Username != "johndoe" {
+		t.Errorf("expected a list of one owner to stay a list, got: %v", actual.Owners)
+	}
+
+	if actual.Timeout != "5s" || actual.Replica != 3 {
+		t.Errorf("expected one element arrays to unwrap, got: %q, %d", actual.Timeout, actual.Replica)
+	}
+
+	input["Replica"] = [2]any{1.0, 2.0}
+	if err := weak.Parse(input, new(Config)); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected an array of two to be rejected for a single value, got: %v", err)
+	}
+}
+
+func TestParseRuneStrings(t *testing.T) {
+	weak := NewParser(WithWeaklyTypedInput(true))
+
+	type CSV struct {
+		Separator rune
+		Quote     rune
+		Comment   int32
+		Columns   int32
+	}
+
+	actual := new(CSV)
+	err := weak.Parse(map[string]any{"Separator": ";", "Quote": "’", "Comment": "#", "Columns": "12"}, actual)
+	if err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	expected := &CSV{Separator: ';', Quote: '’', Comment: '#', Columns: 12}
+	if *actual != *expected {
+		t.Errorf("Parse result not as expected.\nGot: %+v\nExpected: %+v", actual, expected)
+	}
+
+	for _, input := range []string{"ab", "", "\xff"} {
+		var r rune
+		if err := weak.Parse(input, &r); !errors.Is(err, ErrTypeMismatch) {
+			t.Errorf("expected %q to be rejected, got: %v, %v", input, r, err)
+		}
+	}
+
+	var r rune
+	if err := Parse(";", &r); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected a character to be rejected without weak typing, got: %v", err)
+	}
+
+	var i int64
+	if err := weak.Parse("a", &i); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected a character to be rejected for other integers, got: %v", err)
+	}
+}
+
+func TestParseByteStrings(t *testing.T) {
+	type Login struct {
+		Username Username
+		Password []byte
+	}
+
+	input := map[string]any{"Username": []byte("johndoe"), "Password": []byte("secret")}
+
+	if err := Parse(input, new(Login)); !errors.Is(err, ErrTypeMismatch) {
+		t.Errorf("expected bytes not to reach ParseString without weak typing, got: %v", err)
+	}
+
+	weak := NewParser(WithWeaklyTypedInput(true))
+
+	var login Login
+	if err := weak.Parse(input, &login); err != nil {
+		t.Fatalf("Parse returned an error: %v", err)
+	}
+
+	if login.Username != "johndoe" || string(login.Password) != "secret" {
+		t.Errorf("expected the bytes to be parsed as text, got: %+v", login)
+	}
+
+	if err := weak.Parse(map[string]any{"Username": []byte("x"), "Password": []byte{}}, new(Login)); err == nil {
+		t.Errorf("expected ParseString to validate the text")
+	}
+
+	err := weak.Parse(map[string]any{"Username": []byte{0xff, 0xfe}, "Password": []byte{}}, new(Login))
+	if err == nil || strings.Contains(err.Error(), "Username must be") {
+		t.Errorf("expected invalid UTF-8 not to be passed to ParseString, got: %v", err)
+	}
+}